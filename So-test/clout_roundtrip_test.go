@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"demo2/clout"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The clout round trip saves the local clout file, reads it back and
+// compares the two with clout.MatchClout, so key order, regenerated vertex
+// IDs and the fixture's volatile keys do not count as differences.
+var _ = Describe("Service Orchestrator clout round trip", Ordered, func() {
+	save := &dcafmultilist.SaveCloutFileAPI
+	read := &dcafmultilist.ReadCloutAPI
+	var data []byte
+	var local *clout.Clout
+
+	BeforeAll(func() {
+		var err error
+		data, err = ioutil.ReadFile(cloutFileName)
+		Expect(err).NotTo(HaveOccurred())
+		local, err = clout.Parse(data)
+		Expect(err).NotTo(HaveOccurred(), "%s is not a clout", cloutFileName)
+	})
+
+	It("should have a valid local clout", func() {
+		Expect(data).To(clout.BeValidClout(read.Integrity), "Invalid clout in %s", cloutFileName)
+	})
+
+	It("should save the clout", func() {
+		responseBody, err := ApiCall("PUT", save.SavecloutURL, string(data))
+		Expect(err).NotTo(HaveOccurred())
+		var saveCloutResponse struct {
+			Message string `json:"message"`
+			Result  string `json:"result"`
+		}
+		Expect(json.Unmarshal(responseBody, &saveCloutResponse)).To(Succeed())
+		Expect(saveCloutResponse.Message).To(Equal(save.ExpectedMessage))
+		Expect(saveCloutResponse.Result).To(Equal(save.ExpectedResult))
+	})
+
+	It("should read back the clout that was saved", func() {
+		responseBody, err := ApiCall("GET", read.ReadCloutURL, "")
+		Expect(err).NotTo(HaveOccurred())
+		var readCloutResponse struct {
+			Message string          `json:"message"`
+			Result  string          `json:"result"`
+			Data    json.RawMessage `json:"data"`
+		}
+		Expect(json.Unmarshal(responseBody, &readCloutResponse)).To(Succeed())
+		Expect(readCloutResponse.Message).To(Equal(read.ExpectedMessage))
+		Expect(readCloutResponse.Result).To(Equal(read.ExpectedResult))
+		Expect([]byte(readCloutResponse.Data)).To(clout.BeValidClout(read.Integrity), "Invalid clout read back")
+		Expect([]byte(readCloutResponse.Data)).To(clout.MatchClout(local, read.VolatileKeys...), "Clout read back differs from %s", cloutFileName)
+	})
+})
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// instanceOrder only looks at the fixture graph, so it is tested here with the
+// standard library rather than in the Ginkgo suite, which needs a running
+// orchestrator.
+
+func TestInstanceOrder(t *testing.T) {
+	tests := []struct {
+		description string
+		nodes       []InstanceNode
+		order       []string
+		err         string
+	}{
+		{
+			description: "dependencies first",
+			nodes: []InstanceNode{
+				{Name: "demo-dashboard", DependsOn: []string{"demo-collector", "demo-cluster"}},
+				{Name: "demo-collector", DependsOn: []string{"demo-cluster"}},
+				{Name: "demo-cluster"},
+			},
+			order: []string{"demo-cluster", "demo-collector", "demo-dashboard"},
+		},
+		{
+			description: "fixture order among independent instances",
+			nodes:       []InstanceNode{{Name: "demo-b"}, {Name: "demo-a"}},
+			order:       []string{"demo-b", "demo-a"},
+		},
+		{
+			description: "a dependency cycle",
+			nodes: []InstanceNode{
+				{Name: "demo-a", DependsOn: []string{"demo-b"}},
+				{Name: "demo-b", DependsOn: []string{"demo-a"}},
+			},
+			err: "dependency cycle among demo-a, demo-b",
+		},
+		{
+			description: "an instance depending on itself",
+			nodes:       []InstanceNode{{Name: "demo-a", DependsOn: []string{"demo-a"}}},
+			err:         "dependency cycle among demo-a",
+		},
+		{
+			description: "a dangling dependency",
+			nodes:       []InstanceNode{{Name: "demo-orphan", DependsOn: []string{"demo-missing"}}},
+			err:         "depends on unknown instance demo-missing",
+		},
+		{
+			description: "a duplicate instance",
+			nodes:       []InstanceNode{{Name: "demo-a"}, {Name: "demo-a"}},
+			err:         "instance demo-a is declared twice",
+		},
+	}
+	for _, test := range tests {
+		order, err := instanceOrder(test.nodes)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got %v, %v, expected an error containing %q", test.description, order, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.description, err)
+			continue
+		}
+		if !reflect.DeepEqual(order, test.order) {
+			t.Errorf("%s: got %v, expected %v", test.description, order, test.order)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// InstanceNode is one instance of a fixture graph and the instances it
+// depends on.
+type InstanceNode struct {
+	Name      string   `json:"name"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// RejectedCreate is an instance the orchestrator must refuse to create
+// because of its dependencies.
+type RejectedCreate struct {
+	Description     string       `json:"description"`
+	Instance        InstanceNode `json:"instance"`
+	ExpectedStatus  int          `json:"expectedStatus"`
+	ExpectedResult  string       `json:"expectedResult"`
+	ExpectedMessage string       `json:"expectedMessage"`
+}
+
+// The instance graph spec creates parent and child instances in dependency
+// order, checks that the orchestrator links them, and checks what happens
+// when a parent is deleted while it still has dependents.
+// It creates and deletes instances other specs count, hence Serial.
+var _ = Describe("Service Orchestrator instance graph", Ordered, Serial, func() {
+	graph := &dcafmultilist.InstanceGraphAPI
+	var pollInterval, timeout time.Duration
+	var order []string
+	deleted := map[string]bool{}
+
+	BeforeAll(func() {
+		var err error
+		pollInterval, err = time.ParseDuration(graph.PollInterval)
+		Expect(err).NotTo(HaveOccurred(), "Invalid pollInterval")
+		timeout, err = time.ParseDuration(graph.Timeout)
+		Expect(err).NotTo(HaveOccurred(), "Invalid timeout")
+		order, err = instanceOrder(graph.Instances)
+		Expect(err).NotTo(HaveOccurred(), "Invalid instance graph")
+	})
+
+	AfterAll(func() {
+		for i := len(order) - 1; i >= 0; i-- {
+			if !deleted[order[i]] {
+				_, err := ApiCall("DELETE", graph.DeleteInstanceURL+order[i], "")
+				Expect(err).NotTo(HaveOccurred())
+			}
+		}
+	})
+
+	It("should create the instances in dependency order", func() {
+		for _, name := range order {
+			body, err := createInstanceBody(graph.CreateInstanceBody, instanceNode(graph.Instances, name))
+			Expect(err).NotTo(HaveOccurred())
+			status, responseBody, err := apiCallStatus("POST", graph.CreateInstanceURL, body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(BeNumerically("<", 300), "Creating %s failed: %s", name, responseBody)
+			Eventually(getInstanceNames).WithArguments(graph.GetInstancesURL).
+				WithPolling(pollInterval).WithTimeout(timeout).
+				Should(ContainElement(name))
+		}
+	})
+
+	It("should link every instance to the instances it depends on", func() {
+		instances, err := getInstances(graph.GetInstancesURL)
+		Expect(err).NotTo(HaveOccurred())
+		byName := map[string]InstanceData{}
+		for _, instance := range instances {
+			byName[instance.Name] = instance
+		}
+		for _, node := range graph.Instances {
+			instance, ok := byName[node.Name]
+			Expect(ok).To(BeTrue(), "Instance %s is not listed", node.Name)
+			Expect(dependencies(instance.DependentInstance)).To(ConsistOf(node.DependsOn), "Links of %s", node.Name)
+		}
+	})
+
+	It("should only list links to existing instances", func() {
+		instances, err := getInstances(graph.GetInstancesURL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(danglingLinks(instances)).To(BeEmpty())
+	})
+
+	It("should "+graph.ParentDeletePolicy+" deleting a parent with dependents", func() {
+		parent := firstParent(order, graph.Instances)
+		Expect(parent).NotTo(BeEmpty(), "The instance graph has no parent")
+
+		status, responseBody, err := apiCallStatus("DELETE", graph.DeleteInstanceURL+parent, "")
+		Expect(err).NotTo(HaveOccurred())
+		switch graph.ParentDeletePolicy {
+		case "refuse":
+			Expect(status).To(BeNumerically(">=", 400), "Deleting %s was not refused: %s", parent, responseBody)
+			Expect(getInstanceNames(graph.GetInstancesURL)).To(ContainElement(parent))
+		case "cascade":
+			Expect(status).To(BeNumerically("<", 300), "Deleting %s failed: %s", parent, responseBody)
+			removed := append(dependents(graph.Instances, parent), parent)
+			for _, name := range removed {
+				deleted[name] = true
+			}
+			Eventually(getInstanceNames).WithArguments(graph.GetInstancesURL).
+				WithPolling(pollInterval).WithTimeout(timeout).
+				ShouldNot(ContainElements(removed))
+		default:
+			Fail(fmt.Sprintf("Unknown parentDeletePolicy %q", graph.ParentDeletePolicy))
+		}
+	})
+
+	It("should delete the instances dependents first", func() {
+		var remaining []string
+		for i := len(order) - 1; i >= 0; i-- {
+			name := order[i]
+			if deleted[name] {
+				continue
+			}
+			status, responseBody, err := apiCallStatus("DELETE", graph.DeleteInstanceURL+name, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(BeNumerically("<", 300), "Deleting %s failed: %s", name, responseBody)
+			deleted[name] = true
+			remaining = append(remaining, name)
+		}
+		Eventually(getInstanceNames).WithArguments(graph.GetInstancesURL).
+			WithPolling(pollInterval).WithTimeout(timeout).
+			ShouldNot(ContainElements(remaining))
+	})
+})
+
+// The rejected creates are sent to the orchestrator, which must refuse them.
+// A create it wrongly accepts is deleted again, but briefly adds to the
+// instance list, hence Serial.
+var _ = Describe("Service Orchestrator instance graph validation", Serial, func() {
+	graph := &dcafmultilist.InstanceGraphAPI
+
+	for _, rejected := range graph.RejectedCreates {
+		rejected := rejected
+		It("should refuse to create "+rejected.Description, func() {
+			body, err := createInstanceBody(graph.CreateInstanceBody, rejected.Instance)
+			Expect(err).NotTo(HaveOccurred())
+			status, responseBody, err := apiCallStatus("POST", graph.CreateInstanceURL, body)
+			Expect(err).NotTo(HaveOccurred())
+			if status < 300 {
+				DeferCleanup(ApiCall, "DELETE", graph.DeleteInstanceURL+rejected.Instance.Name, "")
+			}
+			Expect(status).To(BeNumerically(">=", 400), "Creating %s was not refused: %s", rejected.Instance.Name, responseBody)
+			if rejected.ExpectedStatus != 0 {
+				Expect(status).To(Equal(rejected.ExpectedStatus))
+			}
+			var envelope struct {
+				Result  string `json:"result"`
+				Message string `json:"message"`
+			}
+			Expect(json.Unmarshal(responseBody, &envelope)).To(Succeed(), "Response is not an envelope: %s", responseBody)
+			if rejected.ExpectedResult != "" {
+				Expect(envelope.Result).To(Equal(rejected.ExpectedResult))
+			}
+			if rejected.ExpectedMessage != "" {
+				Expect(envelope.Message).To(ContainSubstring(rejected.ExpectedMessage))
+			}
+		})
+	}
+})
+
+// instanceOrder returns the instance names so that every instance comes after
+// the instances it depends on, keeping fixture order otherwise. Dangling and
+// cyclic dependencies are errors.
+func instanceOrder(nodes []InstanceNode) ([]string, error) {
+	known := map[string]bool{}
+	for _, node := range nodes {
+		if known[node.Name] {
+			return nil, fmt.Errorf("instance %s is declared twice", node.Name)
+		}
+		known[node.Name] = true
+	}
+	for _, node := range nodes {
+		for _, dependency := range node.DependsOn {
+			if !known[dependency] {
+				return nil, fmt.Errorf("instance %s depends on unknown instance %s", node.Name, dependency)
+			}
+		}
+	}
+
+	placed := map[string]bool{}
+	var order []string
+	for len(order) < len(nodes) {
+		progress := false
+		for _, node := range nodes {
+			if placed[node.Name] || !allPlaced(node.DependsOn, placed) {
+				continue
+			}
+			placed[node.Name] = true
+			order = append(order, node.Name)
+			progress = true
+		}
+		if !progress {
+			var cyclic []string
+			for _, node := range nodes {
+				if !placed[node.Name] {
+					cyclic = append(cyclic, node.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle among %s", strings.Join(cyclic, ", "))
+		}
+	}
+	return order, nil
+}
+
+func allPlaced(names []string, placed map[string]bool) bool {
+	for _, name := range names {
+		if !placed[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// createInstanceBody sets the name and dependent_instance of the fixture's
+// create body for node.
+func createInstanceBody(base string, node InstanceNode) (string, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(base), &body); err != nil {
+		return "", err
+	}
+	body["name"] = node.Name
+	dependsOn := node.DependsOn
+	if dependsOn == nil {
+		dependsOn = []string{}
+	}
+	body["dependent_instance"] = dependsOn
+	data, err := json.Marshal(body)
+	return string(data), err
+}
+
+func instanceNode(nodes []InstanceNode, name string) InstanceNode {
+	for _, node := range nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	return InstanceNode{Name: name}
+}
+
+// firstParent returns the first instance in order that others depend on.
+func firstParent(order []string, nodes []InstanceNode) string {
+	for _, name := range order {
+		if len(dependents(nodes, name)) > 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// dependents returns every instance depending on name, directly or not.
+func dependents(nodes []InstanceNode, name string) []string {
+	var found []string
+	seen := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, node := range nodes {
+			if !seen[node.Name] && containsString(node.DependsOn, current) {
+				seen[node.Name] = true
+				found = append(found, node.Name)
+				queue = append(queue, node.Name)
+			}
+		}
+	}
+	return found
+}
+
+// dependencies drops the empty entry the orchestrator reports for an
+// instance without dependencies.
+func dependencies(links []string) []string {
+	var names []string
+	for _, link := range links {
+		if link != "" {
+			names = append(names, link)
+		}
+	}
+	return names
+}
+
+// danglingLinks reports every dependent_instance link to an instance that is
+// not listed.
+func danglingLinks(instances []InstanceData) []string {
+	listed := map[string]bool{}
+	for _, instance := range instances {
+		listed[instance.Name] = true
+	}
+	var dangling []string
+	for _, instance := range instances {
+		for _, dependency := range dependencies(instance.DependentInstance) {
+			if !listed[dependency] {
+				dangling = append(dangling, instance.Name+" -> "+dependency)
+			}
+		}
+	}
+	return dangling
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The instance lifecycle spec creates its own instance and waits for each
+// asynchronous step of the deployment instead of querying right away. Every
+// step is its own spec so a report shows where the lifecycle stalled. It
+// changes the instance list other specs count, hence Serial.
+var _ = Describe("Service Orchestrator instance lifecycle", Ordered, Serial, func() {
+	lifecycle := &dcafmultilist.InstanceLifecycleAPI
+	var pollInterval, timeout time.Duration
+	deleted := false
+
+	BeforeAll(func() {
+		var err error
+		pollInterval, err = time.ParseDuration(lifecycle.PollInterval)
+		Expect(err).NotTo(HaveOccurred(), "Invalid pollInterval")
+		timeout, err = time.ParseDuration(lifecycle.Timeout)
+		Expect(err).NotTo(HaveOccurred(), "Invalid timeout")
+	})
+
+	AfterAll(func() {
+		if !deleted {
+			_, err := ApiCall("DELETE", lifecycle.DeleteInstanceURL, "")
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should create the instance", func() {
+		_, err := ApiCall("POST", lifecycle.CreateInstanceURL, lifecycle.CreateInstanceBody)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should eventually list the instance", func() {
+		Eventually(getInstanceNames).WithArguments(lifecycle.GetInstancesURL).
+			WithPolling(pollInterval).WithTimeout(timeout).
+			Should(ContainElement(lifecycle.InstanceName))
+	})
+
+	It("should eventually list the instance as deployed", func() {
+		Eventually(getDeployedInstances).WithArguments(lifecycle.DeployedInstancesURL).
+			WithPolling(pollInterval).WithTimeout(timeout).
+			Should(ContainElement(lifecycle.InstanceName))
+	})
+
+	It("should eventually have the clout saved", func() {
+		Eventually(func(g Gomega) {
+			responseBody, err := ApiCall("GET", lifecycle.ReadCloutURL, "")
+			g.Expect(err).NotTo(HaveOccurred())
+			var readCloutResponse struct {
+				Result string          `json:"result"`
+				Data   json.RawMessage `json:"data"`
+			}
+			g.Expect(json.Unmarshal(responseBody, &readCloutResponse)).To(Succeed())
+			g.Expect(readCloutResponse.Result).To(Equal(lifecycle.ExpectedCloutResult))
+			g.Expect(readCloutResponse.Data).NotTo(BeEmpty())
+		}).WithPolling(pollInterval).WithTimeout(timeout).Should(Succeed())
+	})
+
+	It("should delete the instance", func() {
+		_, err := ApiCall("DELETE", lifecycle.DeleteInstanceURL, "")
+		Expect(err).NotTo(HaveOccurred())
+		deleted = true
+	})
+
+	It("should eventually no longer list the instance", func() {
+		Eventually(getInstanceNames).WithArguments(lifecycle.GetInstancesURL).
+			WithPolling(pollInterval).WithTimeout(timeout).
+			ShouldNot(ContainElement(lifecycle.InstanceName))
+	})
+
+	It("should eventually no longer list the instance as deployed", func() {
+		Eventually(getDeployedInstances).WithArguments(lifecycle.DeployedInstancesURL).
+			WithPolling(pollInterval).WithTimeout(timeout).
+			ShouldNot(ContainElement(lifecycle.InstanceName))
+	})
+})
+
+// getInstances returns every instance the orchestrator lists.
+func getInstances(getInstancesURL string) ([]InstanceData, error) {
+	responseBody, err := ApiCall("GET", getInstancesURL, "")
+	if err != nil {
+		return nil, err
+	}
+	var instances []InstanceData
+	if err := json.Unmarshal(responseBody, &instances); err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+// getInstanceNames returns the name of every instance the orchestrator lists.
+func getInstanceNames(getInstancesURL string) ([]string, error) {
+	instances, err := getInstances(getInstancesURL)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(instances))
+	for i, instance := range instances {
+		names[i] = instance.Name
+	}
+	return names, nil
+}
+
+// getDeployedInstances returns the names of the deployed instances.
+func getDeployedInstances(deployedInstancesURL string) ([]string, error) {
+	responseBody, err := ApiCall("GET", deployedInstancesURL, "")
+	if err != nil {
+		return nil, err
+	}
+	var deployed DeployedInstancesResponse
+	if err := json.Unmarshal(responseBody, &deployed); err != nil {
+		return nil, err
+	}
+	return deployed.Data, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// PropertyExpectation is the expectation for one instance property: either
+// an exact value or a regular expression the whole value must match.
+type PropertyExpectation struct {
+	Exact   string `json:"exact"`
+	Pattern string `json:"pattern"`
+}
+
+// checkInstanceProperties reports, per instance UID, every expected property
+// that is missing or does not hold the expected value. Properties without an
+// expectation are not checked since instances differ in what they carry. A
+// pattern that does not compile is reported once instead of being matched.
+func checkInstanceProperties(instances []InstanceData, expected map[string]PropertyExpectation) []string {
+	var problems []string
+	patterns := map[string]*regexp.Regexp{}
+	for key, value := range expected {
+		if value.Pattern == "" {
+			continue
+		}
+		pattern, err := regexp.Compile("^(?:" + value.Pattern + ")$")
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("property %s: invalid pattern %q: %v", key, value.Pattern, err))
+			continue
+		}
+		patterns[key] = pattern
+	}
+
+	for _, instance := range instances {
+		for key, value := range expected {
+			actual, ok := instance.Properties[key]
+			pattern, compiled := patterns[key]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s: missing property %s", instance.UID, key))
+			case value.Pattern != "":
+				if compiled && !pattern.MatchString(actual) {
+					problems = append(problems, fmt.Sprintf("%s: %s is %q, expected to match %s", instance.UID, key, actual, value.Pattern))
+				}
+			case actual != value.Exact:
+				problems = append(problems, fmt.Sprintf("%s: %s is %q, expected %q", instance.UID, key, actual, value.Exact))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// checkGrammarVersions reports, per instance UID, every grammarversion that
+// is not one of allowed.
+func checkGrammarVersions(instances []InstanceData, allowed []string) []string {
+	var problems []string
+	for _, instance := range instances {
+		if !containsString(allowed, instance.GrammarVersion) {
+			problems = append(problems, fmt.Sprintf("%s: grammarversion %q is not one of %v", instance.UID, instance.GrammarVersion, allowed))
+		}
+	}
+	return problems
+}
+
+// instanceVersions maps every distinct version to the UIDs carrying it.
+func instanceVersions(instances []InstanceData) map[string][]string {
+	versions := map[string][]string{}
+	for _, instance := range instances {
+		versions[instance.Version] = append(versions[instance.Version], instance.UID)
+	}
+	return versions
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// ParseModelResponse is the reply of the orchestrator's model parse API.
+type ParseModelResponse struct {
+	Result  string      `json:"result"`
+	Message string      `json:"message"`
+	Data    ParsedModel `json:"data"`
+}
+
+// ParsedModel is what the orchestrator made of a service template.
+type ParsedModel struct {
+	NodeTemplates []ParsedNodeTemplate   `json:"nodeTemplates"`
+	Inputs        map[string]ParsedInput `json:"inputs"`
+	Warnings      []string               `json:"warnings"`
+	Errors        []string               `json:"errors"`
+}
+
+type ParsedNodeTemplate struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type ParsedInput struct {
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Default     interface{} `json:"default"`
+}
+
+// The parse spec posts the fixture's service template once and checks the
+// parsed node templates, inputs and diagnostics against the fixture.
+var _ = Describe("Service Orchestrator model parsing", Ordered, func() {
+	parse := &dcafmultilist.ParseModelAPI
+	var parseModelResponse ParseModelResponse
+
+	BeforeAll(func() {
+		status, responseBody, err := apiCallStatus("POST", parse.ParseModelURL, parse.ParseModelBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNumerically("<", 300), "Parse failed: %s", responseBody)
+		Expect(json.Unmarshal(responseBody, &parseModelResponse)).To(Succeed(), "Unexpected parse response: %s", responseBody)
+	})
+
+	It("should have the correct result and message", func() {
+		Expect(parseModelResponse.Result).To(Equal(parse.ExpectedResult))
+		Expect(parseModelResponse.Message).To(Equal(parse.ExpectedMessage))
+	})
+
+	It("should report no errors", func() {
+		Expect(parseModelResponse.Data.Errors).To(BeEmpty())
+	})
+
+	It("should have the expected node templates", func() {
+		nodeTemplates := map[string]string{}
+		for _, nodeTemplate := range parseModelResponse.Data.NodeTemplates {
+			nodeTemplates[nodeTemplate.Name] = nodeTemplate.Type
+		}
+		Expect(parseModelResponse.Data.NodeTemplates).To(HaveLen(parse.ExpectedNodeTemplateCount))
+		Expect(nodeTemplates).To(Equal(parse.ExpectedNodeTemplates))
+	})
+
+	It("should have the expected inputs", func() {
+		names := make([]string, 0, len(parseModelResponse.Data.Inputs))
+		for name := range parseModelResponse.Data.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		Expect(names).To(HaveLen(parse.ExpectedInputCount))
+		Expect(names).To(ConsistOf(parse.ExpectedInputs))
+	})
+
+	It("should only report allowed warnings", func() {
+		for _, warning := range parseModelResponse.Data.Warnings {
+			Expect(allowedWarning(warning, parse.AllowedWarnings)).To(BeTrue(), "Unexpected warning: %s", warning)
+		}
+	})
+})
+
+// allowedWarning reports whether warning contains one of the allowed
+// substrings.
+func allowedWarning(warning string, allowed []string) bool {
+	for _, substring := range allowed {
+		if strings.Contains(warning, substring) {
+			return true
+		}
+	}
+	return false
+}
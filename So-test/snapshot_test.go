@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"demo2/golden"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The snapshot spec compares whole orchestrator responses against the
+// golden files in testdata/golden, which the ExpectedData arrays of the
+// fixture only sample. The instance specs create and delete instances, hence
+// Serial.
+var _ = Describe("Service Orchestrator snapshots", Serial, func() {
+	snapshot := func(name string, url string, setup ...func()) {
+		It("should return the recorded "+name, func() {
+			for _, step := range setup {
+				step()
+			}
+			responseBody, err := ApiCall("GET", url, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(responseBody).To(golden.MatchGolden(dcafmultilist.Snapshots, name))
+		})
+	}
+
+	snapshot("instances", dcafmultilist.GetInstancesAPI.GetInstancesURL)
+	snapshot("demo_instance", dcafmultilist.DemoInstanceAPI.APIURL)
+	snapshot("deployed_instances", dcafmultilist.DeployedInstancesAPI.APIURL)
+	// The clout is saved here rather than relying on the round trip spec,
+	// which may run after this one.
+	snapshot("clout", dcafmultilist.ReadCloutAPI.ReadCloutURL, func() {
+		data, err := ioutil.ReadFile(cloutFileName)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = ApiCall("PUT", dcafmultilist.SaveCloutFileAPI.SavecloutURL, string(data))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
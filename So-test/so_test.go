@@ -6,7 +6,16 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"demo2/clout"
+	"demo2/csar"
+	"demo2/discovery"
+	"demo2/golden"
+	"demo2/namespace"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -17,12 +26,41 @@ const cloutFileName = "gin/compiler/dcaf_service.json"
 
 var dcafmultilist Dcafmultilist
 
-func loadDcafmultilist() {
+// csarSources is where the compiler suite keeps the TOSCA sources of the
+// services the fixtures create instances of.
+var csarSources = filepath.Join("..", "main", "testdata", "csars")
+
+// defaultAddrs are used for fixture placeholders that no fake, proxy or
+// environment variable has published an address for.
+var defaultAddrs = map[string]string{
+	"compiler": "localhost:10010",
+	"so":       "localhost:10000",
+}
+
+func loadDcafmultilist(csars string) {
 	data, err := ioutil.ReadFile("dcafmultilist.json")
 	if err != nil {
 		log.Fatalf("Error reading dcafmultilist file: %v", err)
 	}
-	err = json.Unmarshal(data, &dcafmultilist)
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		log.Fatalf("Error locating testdata: %v", err)
+	}
+	// csarsPath is the CSAR directory as the compiler reports it in namespace
+	// URLs, drive letter included, so snapshots can mask it.
+	defaults := map[string]string{
+		"testdata":  filepath.ToSlash(testdata),
+		"csars":     filepath.ToSlash(csars),
+		"csarsPath": namespace.Path(csars),
+	}
+	for name, addr := range defaultAddrs {
+		defaults[name] = addr
+	}
+	expanded, err := discovery.Expand(string(data), defaults)
+	if err != nil {
+		log.Fatalf("Error resolving dcafmultilist file: %v", err)
+	}
+	err = json.Unmarshal([]byte(expanded), &dcafmultilist)
 	if err != nil {
 		log.Fatalf("Error parsing dcafmultilist file: %v", err)
 	}
@@ -30,17 +68,39 @@ func loadDcafmultilist() {
 
 func TestCompilerApiOperations(t *testing.T) {
 	RegisterFailHandler(Fail)
-	loadDcafmultilist()
+	// The services the fixtures refer to as ${csars}/<name>.csar are built
+	// from the TOSCA sources the compiler suite keeps in main/testdata/csars.
+	// Every parallel process builds its own, since the fixtures are expanded
+	// before the specs are built.
+	csars, err := filepath.Abs(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error locating the CSAR directory: %v", err)
+	}
+	if _, err := csar.BuildAll(csarSources, csars); err != nil {
+		t.Fatalf("Error building the test CSARs: %v", err)
+	}
+	loadDcafmultilist(csars)
 	RunSpecs(t, "Compiler Operations Suite")
 }
 
-var _ = BeforeSuite(func() {
-	apiURL := dcafmultilist.CreateInstanceAPI.CreateInstanceURL
-	apiBody := dcafmultilist.CreateInstanceAPI.CreateInstanceBody
-	_, err := ApiCall("POST", apiURL, apiBody)
+// The suite instance is created and deleted once, by the first parallel
+// process, so that processes do not create or delete it under each other.
+var _ = SynchronizedBeforeSuite(func() {
+	create := &dcafmultilist.CreateInstanceAPI
+	pollInterval, err := time.ParseDuration(create.PollInterval)
+	Expect(err).NotTo(HaveOccurred(), "Invalid pollInterval")
+	timeout, err := time.ParseDuration(create.Timeout)
+	Expect(err).NotTo(HaveOccurred(), "Invalid timeout")
+
+	_, err = ApiCall("POST", create.CreateInstanceURL, create.CreateInstanceBody)
 	Expect(err).NotTo(HaveOccurred())
 
-})
+	// Creating is asynchronous, so wait for the instance to be listed before
+	// the specs query it.
+	Eventually(getInstanceNames).WithArguments(dcafmultilist.GetInstancesAPI.GetInstancesURL).
+		WithPolling(pollInterval).WithTimeout(timeout).
+		Should(ContainElement(create.InstanceName))
+}, func() {})
 
 var _ = Describe("Service Orchestrator APIs", func() {
 	var APIResponseInstances []InstanceData
@@ -52,9 +112,26 @@ var _ = Describe("Service Orchestrator APIs", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
-	It("should return the expected number of instances", func() {
+	// The counts and the deployed list are exact, so these specs do not run
+	// alongside the specs that create and delete instances.
+	It("should return the expected number of instances", Serial, func() {
 		Expect(len(APIResponseInstances)).To(Equal(dcafmultilist.GetInstancesAPI.ExpectedUidCount))
 	})
+
+	It("should have the expected number of distinct versions", Serial, func() {
+		versions := instanceVersions(APIResponseInstances)
+		Expect(versions).To(HaveLen(dcafmultilist.GetInstancesAPI.ExpectedVersionCount), "UIDs by version: %v", versions)
+	})
+
+	It("should have the expected instance properties", func() {
+		problems := checkInstanceProperties(APIResponseInstances, dcafmultilist.GetInstancesAPI.ExpectedProperties)
+		Expect(problems).To(BeEmpty(), "Instance properties differ from expectations:\n%s", strings.Join(problems, "\n"))
+	})
+
+	It("should only use allowed grammar versions", func() {
+		problems := checkGrammarVersions(APIResponseInstances, dcafmultilist.GetInstancesAPI.AllowedGrammarVersions)
+		Expect(problems).To(BeEmpty(), "Unexpected grammar versions:\n%s", strings.Join(problems, "\n"))
+	})
 	var _ = BeforeEach(func() {
 		apiURL := dcafmultilist.DemoInstanceAPI.APIURL
 		responseBody, err := ApiCall("GET", apiURL, "")
@@ -69,13 +146,24 @@ var _ = Describe("Service Orchestrator APIs", func() {
 			Expect(len(demoInstanceResponse.Vertexes)).To(Equal(dcafmultilist.DemoInstanceAPI.ExpectedVtx))
 		})
 
+		It("should have the expected topology", func() {
+			problems := checkTopology(demoInstanceResponse.Vertexes, dcafmultilist.DemoInstanceAPI.ExpectedTopology)
+			if len(problems) > 0 {
+				reportTopology("Instance topology", instanceGraph(demoInstanceResponse.Vertexes))
+			}
+			Expect(problems).To(BeEmpty(), "Topology differs from expectations:\n%s", strings.Join(problems, "\n"))
+		})
+
 		It("should have the correct name", func() {
 			Expect(demoInstanceResponse.Name).To(Equal("demo1"))
 		})
 
-		It("should have empty dependent_instance", func() {
-			Expect(len(demoInstanceResponse.DependentInstance)).To(Equal(1)) // Assuming empty dependent_instance has a single empty string
-			Expect(demoInstanceResponse.DependentInstance[0]).To(Equal(""))
+		It("should only depend on existing instances", func() {
+			names, err := getInstanceNames(dcafmultilist.GetInstancesAPI.GetInstancesURL)
+			Expect(err).NotTo(HaveOccurred())
+			for _, dependency := range dependencies(demoInstanceResponse.DependentInstance) {
+				Expect(names).To(ContainElement(dependency), "Dangling dependent_instance %s", dependency)
+			}
 		})
 
 	})
@@ -89,7 +177,7 @@ var _ = Describe("Service Orchestrator APIs", func() {
 
 	// Add a new Describe block for the new API
 	var _ = Describe("Deployed Instances APIs", func() {
-		It("should return the correct data", func() {
+		It("should return the correct data", Serial, func() {
 			Expect(deployedInstancesResponse.Data).To(Equal(dcafmultilist.DeployedInstancesAPI.ExpectedData))
 		})
 
@@ -104,71 +192,7 @@ var _ = Describe("Service Orchestrator APIs", func() {
 
 })
 
-var _ = BeforeEach(func() {
-	// Read the content of the JSON file
-	jsonData, err := ioutil.ReadFile(cloutFileName)
-	Expect(err).NotTo(HaveOccurred())
-
-	// Unmarshal the JSON data into the struct
-	var dcafmultilist Dcafmultilist
-	err = json.Unmarshal(jsonData, &dcafmultilist)
-	Expect(err).NotTo(HaveOccurred())
-
-	// Get the clout save API URL from the loaded struct
-	saveCloutAPIURL := dcafmultilist.SaveCloutFileAPI.SavecloutURL
-
-	// Make the API call to save the clout file
-	responseBody, err := ApiCall("PUT", saveCloutAPIURL, "")
-	Expect(err).NotTo(HaveOccurred())
-
-	// Unmarshal the response body to check the expected message and result
-	var saveCloutResponse struct {
-		Message string `json:"message"`
-		Result  string `json:"result"`
-	}
-	err = json.Unmarshal(responseBody, &saveCloutResponse)
-	Expect(err).NotTo(HaveOccurred())
-
-	// Check if the message and result match the expected values
-	Expect(saveCloutResponse.Message).To(Equal(dcafmultilist.SaveCloutFileAPI.ExpectedMessage))
-	Expect(saveCloutResponse.Result).To(Equal(dcafmultilist.SaveCloutFileAPI.ExpectedResult))
-})
-
-var _ = BeforeEach(func() {
-	apiURL := dcafmultilist.ReadCloutAPI.ReadCloutURL
-	responseBody, err := ApiCall("GET", apiURL, "")
-	Expect(err).NotTo(HaveOccurred())
-
-	// Unmarshal the response body to check the data and message
-	var readCloutResponse struct {
-		Data    []string `json:"data"`
-		Message string   `json:"message"`
-	}
-	err = json.Unmarshal(responseBody, &readCloutResponse)
-	Expect(err).NotTo(HaveOccurred())
-	Expect(readCloutResponse.Data).To(Equal(dcafmultilist.ReadCloutAPI.ExpectedData))
-	Expect(readCloutResponse.Message).To(Equal(dcafmultilist.ReadCloutAPI.ExpectedMessage))
-})
-
-var _ = BeforeEach(func() {
-	apiURL := dcafmultilist.ReadCloutAPI.ReadCloutURL
-	responseBody, err := ApiCall("GET", apiURL, "")
-	Expect(err).NotTo(HaveOccurred())
-
-	// Unmarshal the response body to check the data and message
-	var readCloutResponse struct {
-		Data    []string `json:"data"`
-		Message string   `json:"message"`
-	}
-	err = json.Unmarshal(responseBody, &readCloutResponse)
-	Expect(err).NotTo(HaveOccurred())
-
-	Expect(readCloutResponse.Data).To(Equal(dcafmultilist.ReadCloutAPI.ExpectedData))
-
-	Expect(readCloutResponse.Message).To(Equal(dcafmultilist.ReadCloutAPI.ExpectedMessage))
-})
-
-var _ = AfterSuite(func() {
+var _ = SynchronizedAfterSuite(func() {}, func() {
 	apiURL := dcafmultilist.DeleteInstanceAPI.DeleteInstanceURL
 	var err error
 	_, err = ApiCall("DELETE", apiURL, ``)
@@ -176,24 +200,34 @@ var _ = AfterSuite(func() {
 })
 
 func ApiCall(apiType string, apiURL string, apiBody string) ([]byte, error) {
+	_, responseBody, err := apiCallStatus(apiType, apiURL, apiBody)
+	return responseBody, err
+}
+
+// apiCallStatus is ApiCall for specs that also need the HTTP status code,
+// such as those expecting a refusal.
+func apiCallStatus(apiType string, apiURL string, apiBody string) (int, []byte, error) {
 	body := bytes.NewReader([]byte(apiBody))
 	request, err := http.NewRequest(apiType, apiURL, body)
 	if err != nil {
 		log.Printf("Error while API call: %s", err)
+		return 0, nil, err
 	}
 	request.Header.Set("Content-Type", "application/json")
 	client := &http.Client{}
 	response, err := client.Do(request)
 	if err != nil {
 		log.Println("Error client.Do(request):", err)
+		return 0, nil, err
 	}
 	defer response.Body.Close()
 
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		log.Println("Error responseBody:", err)
+		return response.StatusCode, nil, err
 	}
-	return responseBody, nil
+	return response.StatusCode, responseBody, nil
 }
 
 type CreateInstanceAPI struct {
@@ -207,7 +241,7 @@ type InstanceData struct {
 	Version           string            `json:"version"`
 	GrammarVersion    string            `json:"grammarversion"`
 	Properties        map[string]string `json:"properties"`
-	Vertexes          []interface{}     `json:"vertexes"`
+	Vertexes          []Vertex          `json:"vertexes"`
 }
 
 type DemoInstanceData struct {
@@ -217,7 +251,7 @@ type DemoInstanceData struct {
 	Version           string            `json:"version"`
 	GrammarVersion    string            `json:"grammarversion"`
 	Properties        map[string]string `json:"properties"`
-	Vertexes          []interface{}     `json:"vertexes"`
+	Vertexes          []Vertex          `json:"vertexes"`
 }
 
 var demoInstanceResponse DemoInstanceData
@@ -234,17 +268,23 @@ type Dcafmultilist struct {
 	CreateInstanceAPI struct {
 		CreateInstanceURL  string `json:"createInstanceURL"`
 		CreateInstanceBody string `json:"createInstanceBody"`
+		InstanceName       string `json:"instanceName"`
+		PollInterval       string `json:"pollInterval"`
+		Timeout            string `json:"timeout"`
 	} `json:"createInstanceAPI"`
 	GetInstancesAPI struct {
-		GetInstancesURL      string `json:"getInstancesURL"`
-		ExpectedResult       string `json:"expectedResult"`
-		ExpectedUidCount     int    `json:"expectedUidCount"`
-		ExpectedVersionCount int    `json:"expectedVersionCount"`
+		GetInstancesURL        string                         `json:"getInstancesURL"`
+		ExpectedResult         string                         `json:"expectedResult"`
+		ExpectedUidCount       int                            `json:"expectedUidCount"`
+		ExpectedVersionCount   int                            `json:"expectedVersionCount"`
+		ExpectedProperties     map[string]PropertyExpectation `json:"expectedProperties"`
+		AllowedGrammarVersions []string                       `json:"allowedGrammarVersions"`
 	} `json:"getInstancesAPI"`
 	DemoInstanceAPI struct {
-		APIURL       string `json:"apiURL"`
-		ExpectedAttr int    `json:"expectedAttributes"`
-		ExpectedVtx  int    `json:"expectedVertexes"`
+		APIURL           string                `json:"apiURL"`
+		ExpectedAttr     int                   `json:"expectedAttributes"`
+		ExpectedVtx      int                   `json:"expectedVertexes"`
+		ExpectedTopology []TopologyExpectation `json:"expectedTopology"`
 	} `json:"demoInstanceAPI"`
 	DeleteInstanceAPI struct {
 		DeleteInstanceURL string `json:"deleteModelURL"`
@@ -261,11 +301,45 @@ type Dcafmultilist struct {
 		ExpectedResult  string `json:"expectedResult"`
 	}
 	ReadCloutAPI struct {
-		ReadCloutURL    string   `json:"readcloutURL"`
-		ExpectedData    []string `json:"expectedData"`
-		ExpectedMessage string   `json:"expectedMessage"`
+		ReadCloutURL    string      `json:"readcloutURL"`
+		ExpectedMessage string      `json:"expectedMessage"`
+		ExpectedResult  string      `json:"expectedResult"`
+		VolatileKeys    []string    `json:"volatileKeys"`
+		Integrity       clout.Rules `json:"integrity"`
 	}
 	ParseModelAPI struct {
-		ParseModelURL string `json:"parseModelURL"`
+		ParseModelURL             string            `json:"parseModelURL"`
+		ParseModelBody            string            `json:"parseModelBody"`
+		ExpectedMessage           string            `json:"expectedMessage"`
+		ExpectedResult            string            `json:"expectedResult"`
+		ExpectedNodeTemplateCount int               `json:"expectedNodeTemplateCount"`
+		ExpectedNodeTemplates     map[string]string `json:"expectedNodeTemplates"`
+		ExpectedInputCount        int               `json:"expectedInputCount"`
+		ExpectedInputs            []string          `json:"expectedInputs"`
+		AllowedWarnings           []string          `json:"allowedWarnings"`
 	}
+	InstanceLifecycleAPI struct {
+		CreateInstanceURL    string `json:"createInstanceURL"`
+		CreateInstanceBody   string `json:"createInstanceBody"`
+		InstanceName         string `json:"instanceName"`
+		GetInstancesURL      string `json:"getInstancesURL"`
+		DeployedInstancesURL string `json:"deployedInstancesURL"`
+		ReadCloutURL         string `json:"readCloutURL"`
+		ExpectedCloutResult  string `json:"expectedCloutResult"`
+		DeleteInstanceURL    string `json:"deleteInstanceURL"`
+		PollInterval         string `json:"pollInterval"`
+		Timeout              string `json:"timeout"`
+	} `json:"instanceLifecycleAPI"`
+	InstanceGraphAPI struct {
+		CreateInstanceURL  string           `json:"createInstanceURL"`
+		CreateInstanceBody string           `json:"createInstanceBody"`
+		GetInstancesURL    string           `json:"getInstancesURL"`
+		DeleteInstanceURL  string           `json:"deleteInstanceURL"`
+		Instances          []InstanceNode   `json:"instances"`
+		ParentDeletePolicy string           `json:"parentDeletePolicy"`
+		RejectedCreates    []RejectedCreate `json:"rejectedCreates"`
+		PollInterval       string           `json:"pollInterval"`
+		Timeout            string           `json:"timeout"`
+	} `json:"instanceGraphAPI"`
+	Snapshots golden.Config `json:"snapshots"`
 }
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"demo2/clout"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+// Vertex is one node of an instance topology. The orchestrator reports
+// vertexes either flat or in the clout layout, where kind sits under
+// metadata.puccini and name, types and properties under properties;
+// UnmarshalJSON accepts both. Only the clout layout records the type
+// hierarchy in Parents; flat types are listed most derived first.
+type Vertex struct {
+	ID         string                 `json:"id"`
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Types      []string               `json:"types"`
+	Parents    map[string]string      `json:"-"`
+	Properties map[string]interface{} `json:"properties"`
+	EdgesOut   []Edge                 `json:"edgesOut"`
+}
+
+// Edge is a relationship from one vertex to the vertex with TargetID.
+type Edge struct {
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Types      []string               `json:"types"`
+	Parents    map[string]string      `json:"-"`
+	Properties map[string]interface{} `json:"properties"`
+	TargetID   string                 `json:"targetID"`
+}
+
+// TopologyExpectation is one line of a fixture's topology: node Node of type
+// Type exists and, when ConnectedTo is set, has a Relationship edge to it.
+type TopologyExpectation struct {
+	Node         string                 `json:"node"`
+	Type         string                 `json:"type"`
+	Properties   map[string]interface{} `json:"properties"`
+	ConnectedTo  string                 `json:"connectedTo"`
+	Relationship string                 `json:"relationship"`
+}
+
+// cloutEntity is the clout layout shared by vertexes and edges.
+type cloutEntity struct {
+	Metadata struct {
+		Puccini struct {
+			Kind string `json:"kind"`
+		} `json:"puccini"`
+	} `json:"metadata"`
+	Properties struct {
+		Name  string `json:"name"`
+		Types map[string]struct {
+			Parent string `json:"parent"`
+		} `json:"types"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"properties"`
+}
+
+func (entity cloutEntity) types() []string {
+	types := make([]string, 0, len(entity.Properties.Types))
+	for name := range entity.Properties.Types {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// parents maps every type of the entity to its parent type.
+func (entity cloutEntity) parents() map[string]string {
+	parents := map[string]string{}
+	for name, entry := range entity.Properties.Types {
+		if entry.Parent != "" {
+			parents[name] = entry.Parent
+		}
+	}
+	return parents
+}
+
+func (vertex *Vertex) UnmarshalJSON(data []byte) error {
+	type flat Vertex
+	if err := json.Unmarshal(data, (*flat)(vertex)); err == nil && vertex.Kind != "" {
+		return nil
+	}
+	var clout struct {
+		cloutEntity
+		ID       string `json:"id"`
+		EdgesOut []Edge `json:"edgesOut"`
+	}
+	if err := json.Unmarshal(data, &clout); err != nil {
+		return err
+	}
+	*vertex = Vertex{
+		ID:         clout.ID,
+		Kind:       clout.Metadata.Puccini.Kind,
+		Name:       clout.Properties.Name,
+		Types:      clout.types(),
+		Parents:    clout.parents(),
+		Properties: clout.Properties.Properties,
+		EdgesOut:   clout.EdgesOut,
+	}
+	return nil
+}
+
+func (edge *Edge) UnmarshalJSON(data []byte) error {
+	type flat Edge
+	if err := json.Unmarshal(data, (*flat)(edge)); err == nil && edge.Kind != "" {
+		return nil
+	}
+	var clout struct {
+		cloutEntity
+		TargetID string `json:"targetID"`
+	}
+	if err := json.Unmarshal(data, &clout); err != nil {
+		return err
+	}
+	*edge = Edge{
+		Kind:       clout.Metadata.Puccini.Kind,
+		Name:       clout.Properties.Name,
+		Types:      clout.types(),
+		Parents:    clout.parents(),
+		Properties: clout.Properties.Properties,
+		TargetID:   clout.TargetID,
+	}
+	return nil
+}
+
+// checkTopology reports the topology expectations vertexes do not meet.
+func checkTopology(vertexes []Vertex, expectations []TopologyExpectation) []string {
+	byName := map[string]Vertex{}
+	byID := map[string]Vertex{}
+	for _, vertex := range vertexes {
+		byName[vertex.Name] = vertex
+		byID[vertex.ID] = vertex
+	}
+
+	var problems []string
+	for _, expected := range expectations {
+		vertex, ok := byName[expected.Node]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("node %s: missing", expected.Node))
+			continue
+		}
+		if expected.Type != "" && !containsString(vertex.Types, expected.Type) {
+			problems = append(problems, fmt.Sprintf("node %s: has types %v, expected %s", expected.Node, vertex.Types, expected.Type))
+		}
+		for key, value := range expected.Properties {
+			actual, ok := vertex.Properties[key]
+			actual = clout.Unwrap(actual)
+			if !ok || !reflect.DeepEqual(actual, value) {
+				problems = append(problems, fmt.Sprintf("node %s: property %s is %v, expected %v", expected.Node, key, actual, value))
+			}
+		}
+		if expected.ConnectedTo == "" {
+			continue
+		}
+		connected := false
+		for _, edge := range vertex.EdgesOut {
+			target, ok := byID[edge.TargetID]
+			if ok && target.Name == expected.ConnectedTo &&
+				(expected.Relationship == "" || edge.Name == expected.Relationship || containsString(edge.Types, expected.Relationship)) {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			problems = append(problems, fmt.Sprintf("node %s: not connected to %s by %s", expected.Node, expected.ConnectedTo, expected.Relationship))
+		}
+	}
+	return problems
+}
+
+// instanceGraph turns instance vertexes into a graph for rendering.
+func instanceGraph(vertexes []Vertex) clout.Graph {
+	var graph clout.Graph
+	known := map[string]bool{}
+	for _, vertex := range vertexes {
+		known[vertex.ID] = true
+	}
+	missing := map[string]bool{}
+	for _, vertex := range vertexes {
+		graph.Nodes = append(graph.Nodes, clout.GraphNode{ID: vertex.ID, Kind: vertex.Kind, Name: vertex.Name, Type: mostDerived(vertex.Types, vertex.Parents)})
+		for _, edge := range vertex.EdgesOut {
+			graph.Edges = append(graph.Edges, clout.GraphEdge{From: vertex.ID, To: edge.TargetID, Name: edge.Name, Type: mostDerived(edge.Types, edge.Parents)})
+			if !known[edge.TargetID] && !missing[edge.TargetID] {
+				missing[edge.TargetID] = true
+				graph.Nodes = append(graph.Nodes, clout.GraphNode{ID: edge.TargetID, Kind: "missing", Name: edge.TargetID})
+			}
+		}
+	}
+	return graph
+}
+
+// reportTopology attaches the graph to the spec's report in DOT and Mermaid,
+// so a failing topology spec can be pictured rather than read as JSON.
+func reportTopology(title string, graph clout.Graph) {
+	AddReportEntry(title+" (DOT)", graph.DOT())
+	AddReportEntry(title+" (Mermaid)", graph.Mermaid())
+}
+
+// mostDerived returns the first of types that no other type names as its
+// parent, as clout.Graph picks the type it renders.
+func mostDerived(types []string, parents map[string]string) string {
+	isParent := map[string]bool{}
+	for _, parent := range parents {
+		isParent[parent] = true
+	}
+	for _, name := range types {
+		if !isParent[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The fixture checks are plain functions of decoded JSON, so they are tested
+// here with the standard library rather than in the Ginkgo suite, which needs
+// a running compiler.
+
+// decode turns a JSON literal into the value the checks see after the inputs
+// endpoint response has been unmarshaled.
+func decode(t *testing.T, literal string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(literal), &value); err != nil {
+		t.Fatalf("decoding %s: %v", literal, err)
+	}
+	return value
+}
+
+func TestCheckDefault(t *testing.T) {
+	tests := []struct {
+		dataTypeName string
+		value        string
+		valid        bool
+	}{
+		{"integer", `100`, true},
+		{"integer", `-3`, true},
+		{"integer", `1e3`, true},
+		{"integer", `2.5`, false},
+		{"integer", `"100"`, false},
+		{"float", `2.5`, true},
+		{"float", `3`, true},
+		{"float", `"2.5"`, false},
+		{"string", `"silver"`, true},
+		{"string", `7`, false},
+		{"boolean", `false`, true},
+		{"boolean", `"false"`, false},
+		{"list", `["a", "b"]`, true},
+		{"list", `{"a": 1}`, false},
+		{"map", `{"a": 1}`, true},
+		{"map", `["a"]`, false},
+		{"range", `[1, 10]`, true},
+		{"range", `[0, "UNBOUNDED"]`, true},
+		{"range", `["UNBOUNDED", 10]`, false},
+		{"range", `[1.5, 10]`, false},
+		{"range", `[1]`, false},
+		{"timestamp", `"2001-12-14T21:59:43.10-05:00"`, true},
+		{"timestamp", `"2002-12-14"`, true},
+		{"timestamp", `"yesterday"`, false},
+		{"version", `"1.2.3.beta-4"`, true},
+		{"version", `1.2`, true},
+		{"version", `"1.x"`, false},
+		{"scalar-unit.size", `"10 MB"`, true},
+		{"scalar-unit.size", `"10 mb"`, true},
+		{"scalar-unit.size", `"10 MiB"`, true},
+		{"scalar-unit.size", `"10 MHz"`, false},
+		{"scalar-unit.size", `10`, false},
+		{"scalar-unit.time", `"1.5 S"`, true},
+		{"scalar-unit.frequency", `"2.4 GHZ"`, true},
+		{"scalar-unit.bitrate", `"100 Kbps"`, true},
+		{"dcaf.datatypes.Thresholds", `{"any": "thing"}`, true},
+		{"integer", `null`, true},
+	}
+	for _, test := range tests {
+		problem := checkDefault(test.dataTypeName, decode(t, test.value))
+		if test.valid && problem != "" {
+			t.Errorf("%s %s: unexpected problem %q", test.dataTypeName, test.value, problem)
+		}
+		if !test.valid && problem == "" {
+			t.Errorf("%s %s: accepted, expected a problem", test.dataTypeName, test.value)
+		}
+	}
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		operator  string
+		argument  string
+		value     string
+		satisfied bool
+	}{
+		{"equal", `"gold"`, `"gold"`, true},
+		{"equal", `"gold"`, `"silver"`, false},
+		{"valid_values", `["gold", "silver", "bronze"]`, `"silver"`, true},
+		{"valid_values", `["gold", "silver", "bronze"]`, `"copper"`, false},
+		{"greater_than", `1`, `2`, true},
+		{"greater_than", `1`, `1`, false},
+		{"greater_or_equal", `1`, `1`, true},
+		{"less_than", `10`, `10`, false},
+		{"less_or_equal", `10`, `10`, true},
+		{"greater_than", `"1 MB"`, `"2 MB"`, true},
+		{"in_range", `[0, 10000]`, `0`, true},
+		{"in_range", `[0, 10000]`, `10000`, true},
+		{"in_range", `[0, 10000]`, `10001`, false},
+		{"in_range", `[0, 10000]`, `-1`, false},
+		{"in_range", `[1, "UNBOUNDED"]`, `1000000`, true},
+		{"in_range", `[1, "UNBOUNDED"]`, `0`, false},
+		{"length", `3`, `"abc"`, true},
+		{"length", `3`, `["a", "b"]`, false},
+		{"min_length", `1`, `{}`, false},
+		{"max_length", `2`, `"ab"`, true},
+		{"pattern", `"[a-z]+"`, `"silver"`, true},
+		{"pattern", `"[a-z]+"`, `"silver1"`, false},
+		{"pattern", `"[a-z]+"`, `"1silver"`, false},
+		{"pattern", `"a|b"`, `"ab"`, false},
+		{"pattern", `"[a-z"`, `"a"`, false},
+		{"pattern", `"[a-z]+"`, `7`, true},
+		{"schema", `"anything"`, `"anything"`, true},
+	}
+	for _, test := range tests {
+		argument, value := decode(t, test.argument), decode(t, test.value)
+		if satisfies(test.operator, argument, value) != test.satisfied {
+			t.Errorf("%s %s with %s: expected satisfied to be %t", test.operator, test.argument, test.value, test.satisfied)
+		}
+	}
+}
+
+func TestCheckEntries(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    string
+		problems int
+	}{
+		{"not a collection", `{"datatypename": "string", "default": "a"}`, 0},
+		{"list without entry_schema", `{"datatypename": "list", "default": ["a"]}`, 1},
+		{"map without entry_schema type", `{"datatypename": "map", "entry_schema": {}}`, 1},
+		{"list of strings", `{"datatypename": "list", "default": ["a", "b"], "entry_schema": {"type": "string"}}`, 0},
+		{"list with a bad entry", `{"datatypename": "list", "default": ["a", 1], "entry_schema": {"type": "string"}}`, 1},
+		{"map of integers", `{"datatypename": "map", "default": {"low": 10, "high": 90}, "entry_schema": {"type": "integer"}}`, 0},
+		{"map with bad entries", `{"datatypename": "map", "default": {"low": 1.5, "high": "90"}, "entry_schema": {"type": "integer"}}`, 2},
+		{"map entries out of range", `{"datatypename": "map", "default": {"low": 10, "high": 190}, "entry_schema": {"type": "integer", "constraints": [{"in_range": [0, 100]}]}}`, 1},
+		{"map without a default", `{"datatypename": "map", "entry_schema": {"type": "integer"}}`, 0},
+	}
+	for _, test := range tests {
+		var event EventData
+		if err := json.Unmarshal([]byte(test.event), &event); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if problems := checkEntries(event); len(problems) != test.problems {
+			t.Errorf("%s: got problems %q, expected %d", test.name, problems, test.problems)
+		}
+	}
+}
+
+func TestCheckInputExpectation(t *testing.T) {
+	var event EventData
+	if err := json.Unmarshal([]byte(`{
+		"datatypename": "list",
+		"name": "modem_ids",
+		"entry_schema": {"type": "string"},
+		"constraints": [{"min_length": 1}],
+		"required": true,
+		"description": "Modems to watch.",
+		"metadata": {"owner": "cmts"}
+	}`), &event); err != nil {
+		t.Fatal(err)
+	}
+	required, optional := true, false
+	tests := []struct {
+		name     string
+		expected InputExpectation
+		problems int
+	}{
+		{"nothing declared", InputExpectation{}, 0},
+		{"everything matches", InputExpectation{
+			DataTypeName: "list",
+			EntrySchema:  "string",
+			Constraints:  []Constraint{{"min_length": float64(1)}},
+			Required:     &required,
+			Description:  "Modems to watch.",
+			Metadata:     map[string]string{"owner": "cmts"},
+		}, 0},
+		{"datatypename", InputExpectation{DataTypeName: "map"}, 1},
+		{"entry_schema", InputExpectation{EntrySchema: "integer"}, 1},
+		{"constraints", InputExpectation{Constraints: []Constraint{{"min_length": float64(2)}}}, 1},
+		{"required", InputExpectation{Required: &optional}, 1},
+		{"description", InputExpectation{Description: "Modems."}, 1},
+		{"metadata", InputExpectation{Metadata: map[string]string{}}, 1},
+		{"several", InputExpectation{DataTypeName: "map", Required: &optional}, 2},
+	}
+	for _, test := range tests {
+		if problems := checkInputExpectation(event, test.expected); len(problems) != test.problems {
+			t.Errorf("%s: got problems %q, expected %d", test.name, problems, test.problems)
+		}
+	}
+
+	event.Required = nil
+	if problems := checkInputExpectation(event, InputExpectation{Required: &required}); len(problems) != 1 {
+		t.Errorf("unset required: got problems %q, expected 1", problems)
+	}
+}
+
+func TestCheckGroups(t *testing.T) {
+	var data map[string][]EventData
+	if err := json.Unmarshal([]byte(`{
+		"dcaf_service": [
+			{"name": "collector_type", "datatypename": "string"},
+			{"name": "upstream_rate_lower", "datatypename": "integer"}
+		],
+		"argo": [
+			{"name": "event_bus", "datatypename": "string"}
+		]
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	one, two := 1, 2
+	tests := []struct {
+		name     string
+		expected map[string]GroupExpectation
+		problems []string
+	}{
+		{"all groups match", map[string]GroupExpectation{
+			"dcaf_service": {Names: []string{"collector_type", "upstream_rate_lower"}, Count: &two, DataTypeCounts: map[string]int{"string": 1, "integer": 1, "list": 0}},
+			"argo":         {Count: &one},
+		}, nil},
+		{"missing group", map[string]GroupExpectation{
+			"dcaf_service": {},
+			"argo":         {},
+			"ran":          {},
+		}, []string{"group ran: missing"}},
+		{"unexpected group", map[string]GroupExpectation{
+			"dcaf_service": {},
+		}, []string{"group argo: unexpected, with 1 inputs"}},
+		{"count", map[string]GroupExpectation{
+			"dcaf_service": {Count: &one},
+			"argo":         {},
+		}, []string{"group dcaf_service: has 2 inputs, expected 1"}},
+		{"names", map[string]GroupExpectation{
+			"dcaf_service": {Names: []string{"collector_type", "stream_processor_type"}},
+			"argo":         {},
+		}, []string{"group dcaf_service: unexpected input upstream_rate_lower", "group dcaf_service: missing input stream_processor_type"}},
+	}
+	for _, test := range tests {
+		problems := checkGroups(data, test.expected)
+		if !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: got problems %q, expected %q", test.name, problems, test.problems)
+		}
+	}
+
+	problems := checkGroups(data, map[string]GroupExpectation{
+		"dcaf_service": {DataTypeCounts: map[string]int{"string": 2}},
+		"argo":         {},
+	})
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "group dcaf_service: dataTypeName counts differ:") {
+		t.Errorf("dataTypeName counts: got problems %q", problems)
+	}
+}
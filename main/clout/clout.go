@@ -0,0 +1,273 @@
+// Package clout loads the clout documents the compiler and the orchestrator
+// write, such as gin/compiler/dcaf_service.json, into typed structures so
+// that specs can assert on node templates, groups, policies and workflows
+// by name and type instead of digging through nested maps.
+//
+// A clout is a graph: vertexes keyed by generated IDs, each with metadata
+// naming its kind, properties, and outgoing edges pointing at other
+// vertexes by ID. TOSCA entities are vertexes of a particular kind.
+package clout
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// Vertex kinds the compiler writes for TOSCA entities.
+const (
+	KindNodeTemplate = "NodeTemplate"
+	KindGroup        = "Group"
+	KindPolicy       = "Policy"
+	KindWorkflow     = "Workflow"
+	KindWorkflowStep = "WorkflowStep"
+)
+
+// Edge kinds linking TOSCA entities.
+const (
+	KindRelationship       = "Relationship"
+	KindMember             = "Member"
+	KindNodeTemplateTarget = "NodeTemplateTarget"
+	KindGroupTarget        = "GroupTarget"
+)
+
+// Clout is a decoded clout document.
+type Clout struct {
+	Version    string                 `json:"version"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Properties map[string]interface{} `json:"properties"`
+	Vertexes   map[string]*Vertex     `json:"vertexes"`
+}
+
+// Vertex is one vertex of the clout graph.
+type Vertex struct {
+	ID         string                 `json:"-"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Properties map[string]interface{} `json:"properties"`
+	EdgesOut   []*Edge                `json:"edgesOut"`
+}
+
+// Edge is an outgoing edge of a vertex. Source and Target are linked by Load
+// and Parse; Target is nil when TargetID names no vertex.
+type Edge struct {
+	Metadata   map[string]interface{} `json:"metadata"`
+	Properties map[string]interface{} `json:"properties"`
+	TargetID   string                 `json:"targetID"`
+
+	Source *Vertex `json:"-"`
+	Target *Vertex `json:"-"`
+}
+
+// Load reads and parses the clout file at path.
+func Load(path string) (*Clout, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	clout, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing clout %s: %v", path, err)
+	}
+	return clout, nil
+}
+
+// Parse decodes a clout. The orchestrator sometimes returns the document as
+// a JSON string rather than an object; both are accepted. A null edge cannot
+// be linked to anything and is an error.
+func Parse(data []byte) (*Clout, error) {
+	var clout Clout
+	if err := json.Unmarshal(unquote(data), &clout); err != nil {
+		return nil, err
+	}
+	if clout.Vertexes == nil {
+		clout.Vertexes = map[string]*Vertex{}
+	}
+	for id, vertex := range clout.Vertexes {
+		if vertex == nil {
+			vertex = &Vertex{}
+			clout.Vertexes[id] = vertex
+		}
+		vertex.ID = id
+	}
+	for id, vertex := range clout.Vertexes {
+		for index, edge := range vertex.EdgesOut {
+			if edge == nil {
+				return nil, fmt.Errorf("vertex %s: edge %d is null", id, index)
+			}
+			edge.Source = vertex
+			edge.Target = clout.Vertexes[edge.TargetID]
+		}
+	}
+	return &clout, nil
+}
+
+// unquote returns the document inside data when data is a JSON string.
+func unquote(data []byte) []byte {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err == nil {
+		return []byte(encoded)
+	}
+	return data
+}
+
+// Kind returns the kind the compiler recorded for the vertex, such as
+// NodeTemplate.
+func (vertex *Vertex) Kind() string {
+	return kind(vertex.Metadata)
+}
+
+// Name returns the TOSCA name of the vertex.
+func (vertex *Vertex) Name() string {
+	name, _ := vertex.Properties["name"].(string)
+	return name
+}
+
+// Types returns the sorted names of the vertex's TOSCA type hierarchy.
+func (vertex *Vertex) Types() []string {
+	return typeNames(vertex.Properties)
+}
+
+// HasType reports whether the vertex is of toscaType or derives from it.
+func (vertex *Vertex) HasType(toscaType string) bool {
+	return hasType(vertex.Properties, toscaType)
+}
+
+// Kind returns the kind the compiler recorded for the edge, such as
+// Relationship.
+func (edge *Edge) Kind() string {
+	return kind(edge.Metadata)
+}
+
+// Name returns the TOSCA name of the edge, such as the requirement a
+// relationship fulfills.
+func (edge *Edge) Name() string {
+	name, _ := edge.Properties["name"].(string)
+	return name
+}
+
+// Types returns the sorted names of the edge's TOSCA type hierarchy.
+func (edge *Edge) Types() []string {
+	return typeNames(edge.Properties)
+}
+
+// HasType reports whether the edge is of toscaType or derives from it.
+func (edge *Edge) HasType(toscaType string) bool {
+	return hasType(edge.Properties, toscaType)
+}
+
+// Vertex returns the vertex of kind with the given TOSCA name.
+func (clout *Clout) Vertex(kind string, name string) (*Vertex, bool) {
+	for _, vertex := range clout.Vertexes {
+		if vertex.Kind() == kind && vertex.Name() == name {
+			return vertex, true
+		}
+	}
+	return nil, false
+}
+
+// VertexesOfKind returns the vertexes of kind, sorted by name.
+func (clout *Clout) VertexesOfKind(kind string) []*Vertex {
+	var vertexes []*Vertex
+	for _, vertex := range clout.Vertexes {
+		if vertex.Kind() == kind {
+			vertexes = append(vertexes, vertex)
+		}
+	}
+	sort.Slice(vertexes, func(i, j int) bool {
+		if vertexes[i].Name() != vertexes[j].Name() {
+			return vertexes[i].Name() < vertexes[j].Name()
+		}
+		return vertexes[i].ID < vertexes[j].ID
+	})
+	return vertexes
+}
+
+// Inputs returns the service template inputs with their value wrappers
+// removed.
+func (clout *Clout) Inputs() map[string]interface{} {
+	return clout.toscaSection("inputs")
+}
+
+// Outputs returns the service template outputs with their value wrappers
+// removed.
+func (clout *Clout) Outputs() map[string]interface{} {
+	return clout.toscaSection("outputs")
+}
+
+func (clout *Clout) toscaSection(name string) map[string]interface{} {
+	tosca, _ := clout.Properties["tosca"].(map[string]interface{})
+	section, _ := tosca[name].(map[string]interface{})
+	values := make(map[string]interface{}, len(section))
+	for key, value := range section {
+		values[key] = Unwrap(value)
+	}
+	return values
+}
+
+// Unwrap strips the {"$primitive": ...}, {"$value": ...} and {"$list": ...}
+// wrappers the compiler puts around coercible values.
+func Unwrap(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, key := range []string{"$primitive", "$value", "$list"} {
+			if wrapped, ok := value[key]; ok {
+				return Unwrap(wrapped)
+			}
+		}
+		unwrapped := map[string]interface{}{}
+		for key, entry := range value {
+			unwrapped[key] = Unwrap(entry)
+		}
+		return unwrapped
+	case []interface{}:
+		unwrapped := make([]interface{}, len(value))
+		for i, entry := range value {
+			unwrapped[i] = Unwrap(entry)
+		}
+		return unwrapped
+	}
+	return value
+}
+
+func kind(metadata map[string]interface{}) string {
+	puccini, _ := metadata["puccini"].(map[string]interface{})
+	kind, _ := puccini["kind"].(string)
+	return kind
+}
+
+func typeNames(properties map[string]interface{}) []string {
+	hierarchy, _ := properties["types"].(map[string]interface{})
+	names := make([]string, 0, len(hierarchy))
+	for name := range hierarchy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func hasType(properties map[string]interface{}, toscaType string) bool {
+	hierarchy, _ := properties["types"].(map[string]interface{})
+	_, ok := hierarchy[toscaType]
+	return ok
+}
+
+func unwrappedMap(properties map[string]interface{}, key string) map[string]interface{} {
+	values, _ := properties[key].(map[string]interface{})
+	unwrapped := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		unwrapped[name] = Unwrap(value)
+	}
+	return unwrapped
+}
+
+func stringList(properties map[string]interface{}, key string) []string {
+	values, _ := properties[key].([]interface{})
+	var strings []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			strings = append(strings, s)
+		}
+	}
+	return strings
+}
@@ -0,0 +1,13 @@
+package clout
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestClout(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Clout Suite")
+}
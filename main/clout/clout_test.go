@@ -0,0 +1,154 @@
+package clout
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const document = `{
+	"version": "1.0",
+	"metadata": {"puccini": {"version": "1.0"}},
+	"properties": {"tosca": {
+		"inputs": {"cluster_name": {"$primitive": "dcaf"}},
+		"outputs": {"endpoint": {"$value": {"$primitive": "http://dcaf"}}}
+	}},
+	"vertexes": {
+		"a1": {
+			"metadata": {"puccini": {"kind": "NodeTemplate"}},
+			"properties": {
+				"name": "collector",
+				"types": {"dcaf.nodes.Collector": {"parent": "tosca.nodes.Root"}, "tosca.nodes.Root": {}},
+				"properties": {"collector_type": {"$primitive": "telegraf"}},
+				"attributes": {},
+				"capabilities": {}
+			},
+			"edgesOut": [{
+				"metadata": {"puccini": {"kind": "Relationship"}},
+				"properties": {"name": "host", "types": {"tosca.relationships.HostedOn": {}}},
+				"targetID": "b2"
+			}]
+		},
+		"b2": {
+			"metadata": {"puccini": {"kind": "NodeTemplate"}},
+			"properties": {"name": "cluster", "types": {"dcaf.nodes.Cluster": {}, "tosca.nodes.Root": {}}},
+			"edgesOut": []
+		},
+		"c3": {
+			"metadata": {"puccini": {"kind": "Group"}},
+			"properties": {"name": "monitoring", "types": {"tosca.groups.Root": {}}},
+			"edgesOut": [{"metadata": {"puccini": {"kind": "Member"}}, "targetID": "a1"}]
+		},
+		"d4": {
+			"metadata": {"puccini": {"kind": "Policy"}},
+			"properties": {"name": "placement", "types": {"tosca.policies.Placement": {}}},
+			"edgesOut": [
+				{"metadata": {"puccini": {"kind": "NodeTemplateTarget"}}, "targetID": "b2"},
+				{"metadata": {"puccini": {"kind": "GroupTarget"}}, "targetID": "c3"}
+			]
+		},
+		"e5": {
+			"metadata": {"puccini": {"kind": "Workflow"}},
+			"properties": {"name": "deploy", "description": "Deploys the service"},
+			"edgesOut": [{"metadata": {"puccini": {"kind": "WorkflowStep"}}, "targetID": "f6"}]
+		},
+		"f6": {
+			"metadata": {"puccini": {"kind": "WorkflowStep"}},
+			"properties": {"name": "install_cluster"},
+			"edgesOut": [{"metadata": {"puccini": {"kind": "OnSuccess"}}, "targetID": "missing"}]
+		}
+	}
+}`
+
+var _ = Describe("Clout", func() {
+	var clout *Clout
+
+	BeforeEach(func() {
+		var err error
+		clout, err = Parse([]byte(document))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should link edges to their vertexes", func() {
+		collector, ok := clout.NodeTemplate("collector")
+		Expect(ok).To(BeTrue())
+		relationships := collector.Relationships()
+		Expect(relationships).To(HaveLen(1))
+		Expect(relationships[0].Name()).To(Equal("host"))
+		Expect(relationships[0].HasType("tosca.relationships.HostedOn")).To(BeTrue())
+		Expect(relationships[0].Source).To(BeIdenticalTo(collector.Vertex))
+		Expect(relationships[0].Target.Name()).To(Equal("cluster"))
+	})
+
+	It("should leave dangling edge targets nil", func() {
+		step, ok := clout.Vertex(KindWorkflowStep, "install_cluster")
+		Expect(ok).To(BeTrue())
+		Expect(step.EdgesOut[0].Target).To(BeNil())
+	})
+
+	It("should look up node templates by name and type", func() {
+		Expect(clout.NodeTemplates()).To(HaveLen(2))
+		_, ok := clout.NodeTemplate("dashboard")
+		Expect(ok).To(BeFalse())
+
+		clusters := clout.NodeTemplatesOfType("dcaf.nodes.Cluster")
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0].Name()).To(Equal("cluster"))
+		Expect(clout.NodeTemplatesOfType("tosca.nodes.Root")).To(HaveLen(2))
+	})
+
+	It("should unwrap node template properties", func() {
+		collector, _ := clout.NodeTemplate("collector")
+		Expect(collector.Types()).To(Equal([]string{"dcaf.nodes.Collector", "tosca.nodes.Root"}))
+		Expect(collector.Properties).To(Equal(map[string]interface{}{"collector_type": "telegraf"}))
+	})
+
+	It("should resolve group members, policy targets and workflow steps", func() {
+		group, ok := clout.Group("monitoring")
+		Expect(ok).To(BeTrue())
+		Expect(group.Members).To(Equal([]string{"collector"}))
+
+		policy, ok := clout.Policy("placement")
+		Expect(ok).To(BeTrue())
+		Expect(policy.Targets).To(ConsistOf("cluster", "monitoring"))
+
+		workflow, ok := clout.Workflow("deploy")
+		Expect(ok).To(BeTrue())
+		Expect(workflow.Description).To(Equal("Deploys the service"))
+		Expect(workflow.Steps).To(Equal([]string{"install_cluster"}))
+	})
+
+	It("should unwrap inputs and outputs", func() {
+		Expect(clout.Inputs()).To(Equal(map[string]interface{}{"cluster_name": "dcaf"}))
+		Expect(clout.Outputs()).To(Equal(map[string]interface{}{"endpoint": "http://dcaf"}))
+	})
+
+	It("should accept a clout encoded as a JSON string", func() {
+		encoded, err := json.Marshal(document)
+		Expect(err).NotTo(HaveOccurred())
+		decoded, err := Parse(encoded)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Vertexes).To(HaveLen(len(clout.Vertexes)))
+	})
+
+	It("should load a clout file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "clout.json")
+		Expect(os.WriteFile(path, []byte(document), 0644)).To(Succeed())
+		loaded, err := Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded.Version).To(Equal("1.0"))
+	})
+
+	It("should reject a document that is not a clout", func() {
+		_, err := Parse([]byte(`[1, 2]`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject a null edge", func() {
+		_, err := Parse([]byte(`{"vertexes": {"a": {"edgesOut": [null]}}}`))
+		Expect(err).To(MatchError(ContainSubstring("vertex a: edge 0 is null")))
+	})
+})
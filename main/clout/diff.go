@@ -0,0 +1,218 @@
+package clout
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeType says whether something was added, removed or changed.
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+// Change is one structural difference between two clouts. Path names what
+// changed by TOSCA identity, for example
+// NodeTemplate:collector/properties/collector_type or
+// NodeTemplate:collector -Relationship:host-> NodeTemplate:cluster.
+type Change struct {
+	Type ChangeType
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+func (change Change) String() string {
+	switch change.Type {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", change.Path, format(change.New))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", change.Path, format(change.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", change.Path, format(change.Old), format(change.New))
+}
+
+// Diff compares two clouts structurally. Vertexes are matched by kind and
+// TOSCA name rather than by their generated IDs, and edges by kind, name and
+// target, so two compilations of the same service template do not differ.
+// Metadata is not compared since it only records tool versions, value
+// wrappers are removed, and keys named in ignoredKeys are skipped wherever
+// they occur in properties.
+func Diff(before *Clout, after *Clout, ignoredKeys ...string) []Change {
+	ignored := map[string]bool{}
+	for _, key := range ignoredKeys {
+		ignored[key] = true
+	}
+	var changes []Change
+	diffValues(&changes, "properties", Unwrap(before.Properties), Unwrap(after.Properties), ignored)
+
+	oldVertexes := identify(before)
+	newVertexes := identify(after)
+	for _, key := range unionKeys(oldVertexes, newVertexes) {
+		oldVertex, inOld := oldVertexes[key]
+		newVertex, inNew := newVertexes[key]
+		switch {
+		case !inNew:
+			changes = append(changes, Change{Type: Removed, Path: key, Old: oldVertex.Types()})
+		case !inOld:
+			changes = append(changes, Change{Type: Added, Path: key, New: newVertex.Types()})
+		default:
+			diffValues(&changes, key, diffable(oldVertex.Properties), diffable(newVertex.Properties), ignored)
+			diffEdges(&changes, key, edgeKeys(oldVertex, oldVertexes), edgeKeys(newVertex, newVertexes), ignored)
+		}
+	}
+	return changes
+}
+
+// identify keys every vertex by kind and name. Vertexes sharing both are
+// told apart by their position in ID order, which is the best a clout
+// offers.
+func identify(clout *Clout) map[string]*Vertex {
+	keyed := map[string]*Vertex{}
+	for _, id := range sortedKeys(clout.Vertexes) {
+		vertex := clout.Vertexes[id]
+		key := vertexKey(vertex)
+		for n := 2; keyed[key] != nil; n++ {
+			key = fmt.Sprintf("%s#%d", vertexKey(vertex), n)
+		}
+		keyed[key] = vertex
+	}
+	return keyed
+}
+
+func vertexKey(vertex *Vertex) string {
+	if vertex.Kind() == "" && vertex.Name() == "" {
+		return "vertex"
+	}
+	return vertex.Kind() + ":" + vertex.Name()
+}
+
+// edgeKeys keys the edges of vertex by kind, name and target identity.
+func edgeKeys(vertex *Vertex, keyed map[string]*Vertex) map[string]*Edge {
+	targets := map[*Vertex]string{}
+	for key, vertex := range keyed {
+		targets[vertex] = key
+	}
+	edges := map[string]*Edge{}
+	for _, edge := range vertex.EdgesOut {
+		target, ok := targets[edge.Target]
+		if !ok {
+			target = "dangling:" + edge.TargetID
+		}
+		key := edge.Kind()
+		if edge.Name() != "" {
+			key += ":" + edge.Name()
+		}
+		key = fmt.Sprintf("-%s-> %s", key, target)
+		base := key
+		for n := 2; edges[key] != nil; n++ {
+			key = fmt.Sprintf("%s#%d", base, n)
+		}
+		edges[key] = edge
+	}
+	return edges
+}
+
+func diffEdges(changes *[]Change, path string, before map[string]*Edge, after map[string]*Edge, ignored map[string]bool) {
+	for _, key := range unionKeys(before, after) {
+		oldEdge, inOld := before[key]
+		newEdge, inNew := after[key]
+		edgePath := path + " " + key
+		switch {
+		case !inNew:
+			*changes = append(*changes, Change{Type: Removed, Path: edgePath, Old: oldEdge.Types()})
+		case !inOld:
+			*changes = append(*changes, Change{Type: Added, Path: edgePath, New: newEdge.Types()})
+		default:
+			diffValues(changes, edgePath, diffable(oldEdge.Properties), diffable(newEdge.Properties), ignored)
+		}
+	}
+}
+
+// diffValues reports the differences between two decoded JSON values,
+// descending into objects so that a change names the innermost key.
+func diffValues(changes *[]Change, path string, before interface{}, after interface{}, ignored map[string]bool) {
+	oldMap, oldIsMap := before.(map[string]interface{})
+	newMap, newIsMap := after.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		if !reflect.DeepEqual(strip(before, ignored), strip(after, ignored)) {
+			*changes = append(*changes, Change{Type: Changed, Path: path, Old: before, New: after})
+		}
+		return
+	}
+	for _, key := range unionKeys(oldMap, newMap) {
+		if ignored[key] {
+			continue
+		}
+		oldValue, inOld := oldMap[key]
+		newValue, inNew := newMap[key]
+		keyPath := path + "/" + key
+		switch {
+		case !inNew:
+			*changes = append(*changes, Change{Type: Removed, Path: keyPath, Old: oldValue})
+		case !inOld:
+			*changes = append(*changes, Change{Type: Added, Path: keyPath, New: newValue})
+		default:
+			diffValues(changes, keyPath, oldValue, newValue, ignored)
+		}
+	}
+}
+
+// strip returns value without the ignored keys, for comparing lists.
+func strip(value interface{}, ignored map[string]bool) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		stripped := map[string]interface{}{}
+		for key, entry := range value {
+			if !ignored[key] {
+				stripped[key] = strip(entry, ignored)
+			}
+		}
+		return stripped
+	case []interface{}:
+		stripped := make([]interface{}, len(value))
+		for i, entry := range value {
+			stripped[i] = strip(entry, ignored)
+		}
+		return stripped
+	}
+	return value
+}
+
+// diffable returns vertex or edge properties without the name, which is
+// already part of the identity, and without value wrappers.
+func diffable(properties map[string]interface{}) interface{} {
+	rest := make(map[string]interface{}, len(properties))
+	for key, value := range properties {
+		if key != "name" {
+			rest[key] = value
+		}
+	}
+	return Unwrap(rest)
+}
+
+func unionKeys[V any](before map[string]V, after map[string]V) []string {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func format(value interface{}) string {
+	if types, ok := value.([]string); ok {
+		return "[" + strings.Join(types, ", ") + "]"
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,72 @@
+package clout
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff", func() {
+	var before *Clout
+
+	BeforeEach(func() {
+		var err error
+		before, err = Parse([]byte(document))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	parse := func(document string) *Clout {
+		clout, err := Parse([]byte(document))
+		Expect(err).NotTo(HaveOccurred())
+		return clout
+	}
+
+	It("should match vertexes by identity rather than ID", func() {
+		rekeyed := strings.NewReplacer(`"a1"`, `"x9"`, `"b2"`, `"y8"`).Replace(document)
+		Expect(Diff(before, parse(rekeyed))).To(BeEmpty())
+		Expect(parse(rekeyed)).To(MatchClout(before))
+	})
+
+	It("should report changed properties by path", func() {
+		changed := strings.Replace(document, `"telegraf"`, `"collectd"`, 1)
+		Expect(Diff(before, parse(changed))).To(ConsistOf(Change{
+			Type: Changed,
+			Path: "NodeTemplate:collector/properties/collector_type",
+			Old:  "telegraf",
+			New:  "collectd",
+		}))
+	})
+
+	It("should report added and removed nodes", func() {
+		renamed := strings.Replace(document, `"name": "cluster"`, `"name": "k8s"`, 1)
+		changes := Diff(before, parse(renamed))
+		var lines []string
+		for _, change := range changes {
+			lines = append(lines, change.String())
+		}
+		Expect(lines).To(ConsistOf(
+			"+ NodeTemplate:k8s: [dcaf.nodes.Cluster, tosca.nodes.Root]",
+			"- NodeTemplate:cluster: [dcaf.nodes.Cluster, tosca.nodes.Root]",
+			"+ NodeTemplate:collector -Relationship:host-> NodeTemplate:k8s: [tosca.relationships.HostedOn]",
+			"- NodeTemplate:collector -Relationship:host-> NodeTemplate:cluster: [tosca.relationships.HostedOn]",
+			"+ Policy:placement -NodeTemplateTarget-> NodeTemplate:k8s: []",
+			"- Policy:placement -NodeTemplateTarget-> NodeTemplate:cluster: []",
+		))
+	})
+
+	It("should skip ignored keys", func() {
+		changed := strings.Replace(document, `"outputs"`, `"timestamp": "now", "outputs"`, 1)
+		Expect(Diff(before, parse(changed))).To(HaveLen(1))
+		Expect(Diff(before, parse(changed), "timestamp")).To(BeEmpty())
+		Expect(changed).To(MatchClout(before, "timestamp"))
+	})
+
+	It("should list the differences when the matcher fails", func() {
+		changed := strings.Replace(document, `"telegraf"`, `"collectd"`, 1)
+		matcher := MatchClout(before)
+		Expect(matcher.Match([]byte(changed))).To(BeFalse())
+		Expect(matcher.FailureMessage(changed)).To(ContainSubstring(
+			"~ NodeTemplate:collector/properties/collector_type: telegraf -> collectd"))
+	})
+})
@@ -0,0 +1,96 @@
+package clout
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/onsi/gomega/types"
+)
+
+// MatchClout succeeds when the actual clout has no structural differences
+// from expected, as reported by Diff. The actual value may be a *Clout or
+// the clout's JSON as []byte or string.
+func MatchClout(expected *Clout, ignoredKeys ...string) types.GomegaMatcher {
+	return &cloutMatcher{expected: expected, ignoredKeys: ignoredKeys}
+}
+
+type cloutMatcher struct {
+	expected    *Clout
+	ignoredKeys []string
+	changes     []Change
+}
+
+func (matcher *cloutMatcher) Match(actual interface{}) (bool, error) {
+	var clout *Clout
+	var err error
+	switch actual := actual.(type) {
+	case *Clout:
+		clout = actual
+	case []byte:
+		clout, err = Parse(actual)
+	case string:
+		clout, err = Parse([]byte(actual))
+	default:
+		return false, fmt.Errorf("MatchClout expects a *Clout, []byte or string, got %T", actual)
+	}
+	if err != nil {
+		return false, err
+	}
+	matcher.changes = Diff(matcher.expected, clout, matcher.ignoredKeys...)
+	return len(matcher.changes) == 0, nil
+}
+
+func (matcher *cloutMatcher) FailureMessage(actual interface{}) string {
+	lines := make([]string, len(matcher.changes))
+	for i, change := range matcher.changes {
+		lines[i] = "    " + change.String()
+	}
+	return fmt.Sprintf("Expected clouts to match, found %d differences (- expected, + actual):\n%s",
+		len(matcher.changes), strings.Join(lines, "\n"))
+}
+
+func (matcher *cloutMatcher) NegatedFailureMessage(actual interface{}) string {
+	return "Expected clouts to differ, found no structural differences"
+}
+
+// BeValidClout succeeds when Validate finds nothing wrong with the actual
+// clout under rules. The actual value may be the clout's JSON as []byte or
+// string, which also catches duplicate vertex IDs, or a *Clout.
+func BeValidClout(rules Rules) types.GomegaMatcher {
+	return &validCloutMatcher{rules: rules}
+}
+
+type validCloutMatcher struct {
+	rules    Rules
+	findings []Finding
+}
+
+func (matcher *validCloutMatcher) Match(actual interface{}) (bool, error) {
+	var err error
+	switch actual := actual.(type) {
+	case *Clout:
+		matcher.findings = actual.Validate(matcher.rules)
+	case []byte:
+		matcher.findings, err = Validate(actual, matcher.rules)
+	case string:
+		matcher.findings, err = Validate([]byte(actual), matcher.rules)
+	default:
+		return false, fmt.Errorf("BeValidClout expects a *Clout, []byte or string, got %T", actual)
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(matcher.findings) == 0, nil
+}
+
+func (matcher *validCloutMatcher) FailureMessage(actual interface{}) string {
+	lines := make([]string, len(matcher.findings))
+	for i, finding := range matcher.findings {
+		lines[i] = "    " + finding.String()
+	}
+	return fmt.Sprintf("Expected a valid clout, found %d problems:\n%s", len(matcher.findings), strings.Join(lines, "\n"))
+}
+
+func (matcher *validCloutMatcher) NegatedFailureMessage(actual interface{}) string {
+	return "Expected an invalid clout, found no problems"
+}
@@ -0,0 +1,205 @@
+package clout
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graph is a topology reduced to what a picture needs: named, typed nodes
+// and labeled edges. Clout.Graph builds one from a clout; suites holding
+// vertexes in another shape, such as the orchestrator's instances, fill it
+// in themselves.
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// GraphNode is a node of a Graph; ID only has to be unique within it.
+type GraphNode struct {
+	ID   string
+	Kind string
+	Name string
+	Type string
+}
+
+// GraphEdge connects the nodes with IDs From and To.
+type GraphEdge struct {
+	From string
+	To   string
+	Name string
+	Type string
+}
+
+// Graph returns the topology of the clout. Edges whose target is missing
+// point at a node of kind "missing" so that the problem shows in the
+// picture.
+func (clout *Clout) Graph() Graph {
+	var graph Graph
+	missing := map[string]bool{}
+	for _, vertex := range clout.sortedVertexes() {
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:   vertex.ID,
+			Kind: vertex.Kind(),
+			Name: vertex.Name(),
+			Type: vertex.Type(),
+		})
+		for _, edge := range vertex.EdgesOut {
+			if edge.Target == nil {
+				missing[edge.TargetID] = true
+			}
+			graph.Edges = append(graph.Edges, GraphEdge{
+				From: vertex.ID,
+				To:   edge.TargetID,
+				Name: edgeLabel(edge),
+				Type: edge.Type(),
+			})
+		}
+	}
+	for _, id := range sortedKeys(missing) {
+		graph.Nodes = append(graph.Nodes, GraphNode{ID: id, Kind: "missing", Name: id})
+	}
+	return graph
+}
+
+// DOT renders the graph in Graphviz DOT.
+func (graph Graph) DOT() string {
+	var builder strings.Builder
+	builder.WriteString("digraph clout {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, node := range graph.Nodes {
+		style := ""
+		if node.Kind == "missing" {
+			style = ", style=dashed, color=red"
+		}
+		fmt.Fprintf(&builder, "\t%s [label=%s%s];\n", dotQuote(node.ID), dotQuote(nodeLabel(node, `\n`)), style)
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&builder, "\t%s -> %s", dotQuote(edge.From), dotQuote(edge.To))
+		if label := edgeLabelText(edge, `\n`); label != "" {
+			fmt.Fprintf(&builder, " [label=%s]", dotQuote(label))
+		}
+		builder.WriteString(";\n")
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (graph Graph) Mermaid() string {
+	ids := map[string]string{}
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+	for i, node := range graph.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+		shape := `["%s"]`
+		if node.Kind == "missing" {
+			shape = `(["%s"])`
+		}
+		fmt.Fprintf(&builder, "    %s"+shape+"\n", ids[node.ID], mermaidEscape(nodeLabel(node, "<br/>")))
+	}
+	for _, edge := range graph.Edges {
+		from, to := ids[edge.From], ids[edge.To]
+		if label := edgeLabelText(edge, "<br/>"); label != "" {
+			fmt.Fprintf(&builder, "    %s -->|\"%s\"| %s\n", from, mermaidEscape(label), to)
+		} else {
+			fmt.Fprintf(&builder, "    %s --> %s\n", from, to)
+		}
+	}
+	return builder.String()
+}
+
+// Type returns the most derived of the vertex's TOSCA types.
+func (vertex *Vertex) Type() string {
+	return mostDerived(vertex.Properties)
+}
+
+// Type returns the most derived of the edge's TOSCA types.
+func (edge *Edge) Type() string {
+	return mostDerived(edge.Properties)
+}
+
+// mostDerived returns the type no other type in the hierarchy names as its
+// parent.
+func mostDerived(properties map[string]interface{}) string {
+	hierarchy, _ := properties["types"].(map[string]interface{})
+	parents := map[string]bool{}
+	for _, entry := range hierarchy {
+		entry, _ := entry.(map[string]interface{})
+		if parent, ok := entry["parent"].(string); ok {
+			parents[parent] = true
+		}
+	}
+	for _, name := range typeNames(properties) {
+		if !parents[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+func (clout *Clout) sortedVertexes() []*Vertex {
+	vertexes := make([]*Vertex, 0, len(clout.Vertexes))
+	for _, vertex := range clout.Vertexes {
+		vertexes = append(vertexes, vertex)
+	}
+	sort.Slice(vertexes, func(i, j int) bool {
+		if vertexKey(vertexes[i]) != vertexKey(vertexes[j]) {
+			return vertexKey(vertexes[i]) < vertexKey(vertexes[j])
+		}
+		return vertexes[i].ID < vertexes[j].ID
+	})
+	return vertexes
+}
+
+// edgeLabel names an edge by its TOSCA name, or by its kind when it has
+// none, as with group members and workflow steps.
+func edgeLabel(edge *Edge) string {
+	if edge.Name() != "" {
+		return edge.Name()
+	}
+	return edge.Kind()
+}
+
+func nodeLabel(node GraphNode, separator string) string {
+	label := node.Name
+	if node.Kind != "" && node.Kind != KindNodeTemplate && node.Kind != "missing" {
+		label = node.Kind + " " + label
+	}
+	if node.Kind == "missing" {
+		label = "missing " + label
+	}
+	if node.Type != "" {
+		label += separator + node.Type
+	}
+	return label
+}
+
+func edgeLabelText(edge GraphEdge, separator string) string {
+	switch {
+	case edge.Name != "" && edge.Type != "":
+		return edge.Name + separator + edge.Type
+	case edge.Type != "":
+		return edge.Type
+	}
+	return edge.Name
+}
+
+// dotQuote quotes s as a DOT string. Backslashes are left alone so that
+// \n breaks label lines.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
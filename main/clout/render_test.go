@@ -0,0 +1,53 @@
+package clout
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Graph", func() {
+	var graph Graph
+
+	BeforeEach(func() {
+		clout, err := Parse([]byte(document))
+		Expect(err).NotTo(HaveOccurred())
+		graph = clout.Graph()
+	})
+
+	It("should label nodes with their most derived type", func() {
+		Expect(graph.Nodes).To(ContainElement(GraphNode{ID: "a1", Kind: KindNodeTemplate, Name: "collector", Type: "dcaf.nodes.Collector"}))
+	})
+
+	It("should add a node for missing edge targets", func() {
+		Expect(graph.Nodes).To(ContainElement(GraphNode{ID: "missing", Kind: "missing", Name: "missing"}))
+		Expect(graph.Edges).To(ContainElement(GraphEdge{From: "f6", To: "missing", Name: "OnSuccess"}))
+	})
+
+	It("should render DOT", func() {
+		dot := graph.DOT()
+		Expect(dot).To(HavePrefix("digraph clout {\n"))
+		Expect(dot).To(ContainSubstring(`"a1" [label="collector\ndcaf.nodes.Collector"];`))
+		Expect(dot).To(ContainSubstring(`"a1" -> "b2" [label="host\ntosca.relationships.HostedOn"];`))
+		Expect(dot).To(ContainSubstring(`"c3" [label="Group monitoring\ntosca.groups.Root"];`))
+		Expect(dot).To(ContainSubstring(`"missing" [label="missing missing", style=dashed, color=red];`))
+	})
+
+	It("should render Mermaid", func() {
+		graph := Graph{
+			Nodes: []GraphNode{
+				{ID: "v1", Name: "collector", Type: "dcaf.nodes.Collector"},
+				{ID: "v2", Name: `say "hi"`},
+			},
+			Edges: []GraphEdge{
+				{From: "v1", To: "v2", Name: "host", Type: "tosca.relationships.HostedOn"},
+				{From: "v2", To: "v1"},
+			},
+		}
+		Expect(graph.Mermaid()).To(Equal(`flowchart LR
+    n0["collector<br/>dcaf.nodes.Collector"]
+    n1["say #quot;hi#quot;"]
+    n0 -->|"host<br/>tosca.relationships.HostedOn"| n1
+    n1 --> n0
+`))
+	})
+})
@@ -0,0 +1,203 @@
+package clout
+
+// NodeTemplate is a TOSCA node template vertex. Its Properties,
+// Attributes and Capabilities hold unwrapped TOSCA values; the raw vertex
+// properties stay reachable through Vertex.
+type NodeTemplate struct {
+	*Vertex
+
+	Description  string
+	Directives   []string
+	Properties   map[string]interface{}
+	Attributes   map[string]interface{}
+	Capabilities map[string]interface{}
+}
+
+// Relationships returns the relationship edges leaving the node template.
+func (nodeTemplate *NodeTemplate) Relationships() []*Edge {
+	return edgesOfKind(nodeTemplate.Vertex, KindRelationship)
+}
+
+// Group is a TOSCA group vertex.
+type Group struct {
+	*Vertex
+
+	Description string
+	Properties  map[string]interface{}
+	// Members are the names of the member node templates.
+	Members []string
+}
+
+// Policy is a TOSCA policy vertex.
+type Policy struct {
+	*Vertex
+
+	Description string
+	Properties  map[string]interface{}
+	// Targets are the names of the node templates and groups the policy
+	// applies to.
+	Targets []string
+}
+
+// Workflow is a TOSCA workflow vertex.
+type Workflow struct {
+	*Vertex
+
+	Description string
+	// Steps are the names of the workflow's steps.
+	Steps []string
+}
+
+// NodeTemplate returns the node template with the given name.
+func (clout *Clout) NodeTemplate(name string) (*NodeTemplate, bool) {
+	vertex, ok := clout.Vertex(KindNodeTemplate, name)
+	if !ok {
+		return nil, false
+	}
+	return newNodeTemplate(vertex), true
+}
+
+// NodeTemplates returns every node template, sorted by name.
+func (clout *Clout) NodeTemplates() []*NodeTemplate {
+	var nodeTemplates []*NodeTemplate
+	for _, vertex := range clout.VertexesOfKind(KindNodeTemplate) {
+		nodeTemplates = append(nodeTemplates, newNodeTemplate(vertex))
+	}
+	return nodeTemplates
+}
+
+// NodeTemplatesOfType returns the node templates of toscaType or a type
+// derived from it, sorted by name.
+func (clout *Clout) NodeTemplatesOfType(toscaType string) []*NodeTemplate {
+	var nodeTemplates []*NodeTemplate
+	for _, nodeTemplate := range clout.NodeTemplates() {
+		if nodeTemplate.HasType(toscaType) {
+			nodeTemplates = append(nodeTemplates, nodeTemplate)
+		}
+	}
+	return nodeTemplates
+}
+
+// Group returns the group with the given name.
+func (clout *Clout) Group(name string) (*Group, bool) {
+	vertex, ok := clout.Vertex(KindGroup, name)
+	if !ok {
+		return nil, false
+	}
+	return newGroup(vertex), true
+}
+
+// Groups returns every group, sorted by name.
+func (clout *Clout) Groups() []*Group {
+	var groups []*Group
+	for _, vertex := range clout.VertexesOfKind(KindGroup) {
+		groups = append(groups, newGroup(vertex))
+	}
+	return groups
+}
+
+// Policy returns the policy with the given name.
+func (clout *Clout) Policy(name string) (*Policy, bool) {
+	vertex, ok := clout.Vertex(KindPolicy, name)
+	if !ok {
+		return nil, false
+	}
+	return newPolicy(vertex), true
+}
+
+// Policies returns every policy, sorted by name.
+func (clout *Clout) Policies() []*Policy {
+	var policies []*Policy
+	for _, vertex := range clout.VertexesOfKind(KindPolicy) {
+		policies = append(policies, newPolicy(vertex))
+	}
+	return policies
+}
+
+// Workflow returns the workflow with the given name.
+func (clout *Clout) Workflow(name string) (*Workflow, bool) {
+	vertex, ok := clout.Vertex(KindWorkflow, name)
+	if !ok {
+		return nil, false
+	}
+	return newWorkflow(vertex), true
+}
+
+// Workflows returns every workflow, sorted by name.
+func (clout *Clout) Workflows() []*Workflow {
+	var workflows []*Workflow
+	for _, vertex := range clout.VertexesOfKind(KindWorkflow) {
+		workflows = append(workflows, newWorkflow(vertex))
+	}
+	return workflows
+}
+
+func newNodeTemplate(vertex *Vertex) *NodeTemplate {
+	description, _ := vertex.Properties["description"].(string)
+	return &NodeTemplate{
+		Vertex:       vertex,
+		Description:  description,
+		Directives:   stringList(vertex.Properties, "directives"),
+		Properties:   unwrappedMap(vertex.Properties, "properties"),
+		Attributes:   unwrappedMap(vertex.Properties, "attributes"),
+		Capabilities: unwrappedMap(vertex.Properties, "capabilities"),
+	}
+}
+
+func newGroup(vertex *Vertex) *Group {
+	description, _ := vertex.Properties["description"].(string)
+	return &Group{
+		Vertex:      vertex,
+		Description: description,
+		Properties:  unwrappedMap(vertex.Properties, "properties"),
+		Members:     targetNames(vertex, KindMember),
+	}
+}
+
+func newPolicy(vertex *Vertex) *Policy {
+	description, _ := vertex.Properties["description"].(string)
+	return &Policy{
+		Vertex:      vertex,
+		Description: description,
+		Properties:  unwrappedMap(vertex.Properties, "properties"),
+		Targets:     targetNames(vertex, KindNodeTemplateTarget, KindGroupTarget),
+	}
+}
+
+func newWorkflow(vertex *Vertex) *Workflow {
+	description, _ := vertex.Properties["description"].(string)
+	var steps []string
+	for _, edge := range vertex.EdgesOut {
+		if edge.Target != nil && edge.Target.Kind() == KindWorkflowStep {
+			steps = append(steps, edge.Target.Name())
+		}
+	}
+	return &Workflow{
+		Vertex:      vertex,
+		Description: description,
+		Steps:       steps,
+	}
+}
+
+func edgesOfKind(vertex *Vertex, kinds ...string) []*Edge {
+	var edges []*Edge
+	for _, edge := range vertex.EdgesOut {
+		for _, kind := range kinds {
+			if edge.Kind() == kind {
+				edges = append(edges, edge)
+				break
+			}
+		}
+	}
+	return edges
+}
+
+func targetNames(vertex *Vertex, kinds ...string) []string {
+	var names []string
+	for _, edge := range edgesOfKind(vertex, kinds...) {
+		if edge.Target != nil {
+			names = append(names, edge.Target.Name())
+		}
+	}
+	return names
+}
@@ -0,0 +1,215 @@
+package clout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// Rules says what Validate expects of a clout beyond its own consistency.
+// Fixtures carry them verbatim.
+type Rules struct {
+	// RequiredProperties lists, per node type, the properties every node
+	// template of that type or a type derived from it must have a value for.
+	RequiredProperties map[string][]string `json:"requiredProperties"`
+	// AllowFunctionCalls accepts Puccini's {"$functionCall": ...} values,
+	// which a clout compiled without coercion legitimately keeps. Raw TOSCA
+	// functions are never accepted.
+	AllowFunctionCalls bool `json:"allowFunctionCalls"`
+}
+
+// Finding is one integrity problem, located by a JSON path into the clout
+// document, such as $.vertexes.a1.edgesOut[0].targetID.
+type Finding struct {
+	Path    string
+	Message string
+}
+
+func (finding Finding) String() string {
+	return finding.Path + ": " + finding.Message
+}
+
+// toscaFunctions are the intrinsic functions a compiler is expected to turn
+// into values or function calls.
+var toscaFunctions = map[string]bool{
+	"concat":               true,
+	"join":                 true,
+	"token":                true,
+	"get_input":            true,
+	"get_property":         true,
+	"get_attribute":        true,
+	"get_operation_output": true,
+	"get_nodes_of_type":    true,
+	"get_artifact":         true,
+}
+
+// Validate checks a clout document for dangling edge targets, duplicate
+// vertex IDs, node templates missing required properties, unresolved
+// intrinsic functions and requirements without a matching relationship.
+// The error is only for documents that are not clouts at all.
+func Validate(data []byte, rules Rules) ([]Finding, error) {
+	data = unquote(data)
+	findings := duplicateVertexIDs(data)
+	clout, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(findings, clout.Validate(rules)...), nil
+}
+
+// Validate is the Validate function for an already parsed clout, which can
+// no longer tell duplicate vertex IDs apart.
+func (clout *Clout) Validate(rules Rules) []Finding {
+	var findings []Finding
+	findings = unresolvedFunctions(findings, "$.properties", clout.Properties, rules)
+	for _, id := range sortedKeys(clout.Vertexes) {
+		vertex := clout.Vertexes[id]
+		path := "$.vertexes" + pathKey(id)
+		findings = unresolvedFunctions(findings, path+".properties", vertex.Properties, rules)
+		for i, edge := range vertex.EdgesOut {
+			edgePath := fmt.Sprintf("%s.edgesOut[%d]", path, i)
+			if edge.Target == nil {
+				findings = append(findings, Finding{edgePath + ".targetID", fmt.Sprintf("no vertex %q", edge.TargetID)})
+			}
+			findings = unresolvedFunctions(findings, edgePath+".properties", edge.Properties, rules)
+		}
+		if vertex.Kind() == KindNodeTemplate {
+			findings = missingProperties(findings, path, vertex, rules)
+			findings = unmatchedRequirements(findings, path, vertex)
+		}
+	}
+	return findings
+}
+
+// duplicateVertexIDs scans the raw document, since decoding keeps only the
+// last of several vertexes sharing an ID.
+func duplicateVertexIDs(data []byte) []Finding {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	var findings []Finding
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return findings
+		}
+		if key != "vertexes" {
+			var skip json.RawMessage
+			if decoder.Decode(&skip) != nil {
+				return findings
+			}
+			continue
+		}
+		if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+			return findings
+		}
+		seen := map[string]bool{}
+		for decoder.More() {
+			token, err := decoder.Token()
+			if err != nil {
+				return findings
+			}
+			id, _ := token.(string)
+			if seen[id] {
+				findings = append(findings, Finding{"$.vertexes" + pathKey(id), "duplicate vertex ID"})
+			}
+			seen[id] = true
+			var skip json.RawMessage
+			if decoder.Decode(&skip) != nil {
+				return findings
+			}
+		}
+		if _, err := decoder.Token(); err != nil {
+			return findings
+		}
+	}
+	return findings
+}
+
+func missingProperties(findings []Finding, path string, vertex *Vertex, rules Rules) []Finding {
+	properties, _ := vertex.Properties["properties"].(map[string]interface{})
+	for _, nodeType := range sortedKeys(rules.RequiredProperties) {
+		if !vertex.HasType(nodeType) {
+			continue
+		}
+		for _, name := range rules.RequiredProperties[nodeType] {
+			if value, ok := properties[name]; !ok || Unwrap(value) == nil {
+				findings = append(findings, Finding{path + ".properties.properties" + pathKey(name),
+					fmt.Sprintf("node template %s of type %s has no value for required property %s", vertex.Name(), nodeType, name)})
+			}
+		}
+	}
+	return findings
+}
+
+// unmatchedRequirements reports requirements that no relationship edge of
+// the same name fulfills.
+func unmatchedRequirements(findings []Finding, path string, vertex *Vertex) []Finding {
+	fulfilled := map[string]bool{}
+	for _, edge := range vertex.EdgesOut {
+		if edge.Kind() == KindRelationship {
+			fulfilled[edge.Name()] = true
+		}
+	}
+	requirements, _ := vertex.Properties["requirements"].([]interface{})
+	for i, requirement := range requirements {
+		requirement, _ := requirement.(map[string]interface{})
+		name, _ := requirement["name"].(string)
+		if !fulfilled[name] {
+			findings = append(findings, Finding{fmt.Sprintf("%s.properties.requirements[%d]", path, i),
+				fmt.Sprintf("requirement %s of node template %s has no matching relationship", name, vertex.Name())})
+		}
+	}
+	return findings
+}
+
+// unresolvedFunctions reports intrinsic functions and function calls left in
+// value. A single-key map only counts as a function when its argument has
+// the shape of one, a string or a list, so that a property named after a
+// function, whose value the compiler wraps, is not mistaken for a call.
+func unresolvedFunctions(findings []Finding, path string, value interface{}, rules Rules) []Finding {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if len(value) == 1 {
+			for key, argument := range value {
+				if toscaFunctions[key] && isFunctionArgument(argument) {
+					return append(findings, Finding{path, fmt.Sprintf("unresolved intrinsic function %s", key)})
+				}
+			}
+		}
+		if call, ok := value["$functionCall"].(map[string]interface{}); ok && !rules.AllowFunctionCalls {
+			name, _ := call["name"].(string)
+			return append(findings, Finding{path, fmt.Sprintf("unevaluated function call %s", name)})
+		}
+		for _, key := range sortedKeys(value) {
+			findings = unresolvedFunctions(findings, path+pathKey(key), value[key], rules)
+		}
+	case []interface{}:
+		for i, entry := range value {
+			findings = unresolvedFunctions(findings, fmt.Sprintf("%s[%d]", path, i), entry, rules)
+		}
+	}
+	return findings
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// pathKey appends key to a JSON path, bracketed when it is not a plain
+// identifier.
+func pathKey(key string) string {
+	if identifier.MatchString(key) {
+		return "." + key
+	}
+	quoted, _ := json.Marshal(key)
+	return "[" + string(quoted) + "]"
+}
+
+func isFunctionArgument(argument interface{}) bool {
+	switch argument.(type) {
+	case string, []interface{}:
+		return true
+	}
+	return false
+}
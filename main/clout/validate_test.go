@@ -0,0 +1,121 @@
+package clout
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const validDocument = `{
+	"version": "1.0",
+	"properties": {"tosca": {"inputs": {"cluster_name": {"$primitive": "dcaf"}}}},
+	"vertexes": {
+		"a1": {
+			"metadata": {"puccini": {"kind": "NodeTemplate"}},
+			"properties": {
+				"name": "collector",
+				"types": {"dcaf.nodes.Collector": {}},
+				"properties": {"collector_type": {"$primitive": "telegraf"}},
+				"requirements": [{"name": "host"}]
+			},
+			"edgesOut": [{
+				"metadata": {"puccini": {"kind": "Relationship"}},
+				"properties": {"name": "host"},
+				"targetID": "b2"
+			}]
+		},
+		"b2": {
+			"metadata": {"puccini": {"kind": "NodeTemplate"}},
+			"properties": {
+				"name": "cluster",
+				"types": {"dcaf.nodes.Cluster": {"parent": "tosca.nodes.Root"}, "tosca.nodes.Root": {}},
+				"properties": {"cluster_name": {"$primitive": "dcaf"}}
+			}
+		}
+	}
+}`
+
+var _ = Describe("Validate", func() {
+	rules := Rules{RequiredProperties: map[string][]string{"tosca.nodes.Root": {"cluster_name"}}}
+
+	validate := func(document string, rules Rules) []string {
+		findings, err := Validate([]byte(document), rules)
+		Expect(err).NotTo(HaveOccurred())
+		var lines []string
+		for _, finding := range findings {
+			lines = append(lines, finding.String())
+		}
+		return lines
+	}
+
+	It("should accept a consistent clout", func() {
+		Expect(validate(validDocument, rules)).To(BeEmpty())
+		Expect(validDocument).To(BeValidClout(rules))
+	})
+
+	It("should report dangling edge targets", func() {
+		dangling := strings.Replace(validDocument, `"targetID": "b2"`, `"targetID": "c3"`, 1)
+		Expect(validate(dangling, Rules{})).To(ConsistOf(`$.vertexes.a1.edgesOut[0].targetID: no vertex "c3"`))
+	})
+
+	It("should report duplicate vertex IDs", func() {
+		duplicate := strings.Replace(validDocument, `"b2": {`, `"a1": {`, 1)
+		Expect(validate(duplicate, Rules{})).To(ContainElement("$.vertexes.a1: duplicate vertex ID"))
+	})
+
+	It("should report missing required properties of derived types", func() {
+		missing := strings.Replace(validDocument, `"cluster_name": {"$primitive": "dcaf"}}
+			}`, `"cluster_name": null}
+			}`, 1)
+		Expect(validate(missing, rules)).To(ConsistOf(
+			"$.vertexes.b2.properties.properties.cluster_name: node template cluster of type tosca.nodes.Root has no value for required property cluster_name"))
+	})
+
+	It("should report unresolved intrinsic functions", func() {
+		unresolved := strings.Replace(validDocument, `{"$primitive": "telegraf"}`, `{"get_input": "collector_type"}`, 1)
+		Expect(validate(unresolved, Rules{})).To(ConsistOf(
+			"$.vertexes.a1.properties.properties.collector_type: unresolved intrinsic function get_input"))
+	})
+
+	It("should not mistake properties named after functions for calls", func() {
+		named := strings.Replace(validDocument, `"properties": {"collector_type": {"$primitive": "telegraf"}}`,
+			`"properties": {"token": {"$primitive": "abc"}}`, 1)
+		Expect(validate(named, Rules{})).To(BeEmpty())
+		wrapped := strings.Replace(validDocument, `{"$primitive": "telegraf"}`, `[{"join": {"$value": "telegraf"}}]`, 1)
+		Expect(validate(wrapped, Rules{})).To(BeEmpty())
+		listed := strings.Replace(validDocument, `{"$primitive": "telegraf"}`, `{"concat": ["tele", "graf"]}`, 1)
+		Expect(validate(listed, Rules{})).To(ConsistOf(
+			"$.vertexes.a1.properties.properties.collector_type: unresolved intrinsic function concat"))
+	})
+
+	It("should only accept function calls when allowed", func() {
+		call := strings.Replace(validDocument, `{"$primitive": "telegraf"}`, `{"$functionCall": {"name": "concat"}}`, 1)
+		Expect(validate(call, Rules{})).To(ConsistOf(
+			"$.vertexes.a1.properties.properties.collector_type: unevaluated function call concat"))
+		Expect(validate(call, Rules{AllowFunctionCalls: true})).To(BeEmpty())
+	})
+
+	It("should report requirements without a relationship", func() {
+		unmatched := strings.Replace(validDocument, `"properties": {"name": "host"}`, `"properties": {"name": "storage"}`, 1)
+		Expect(validate(unmatched, Rules{})).To(ConsistOf(
+			"$.vertexes.a1.properties.requirements[0]: requirement host of node template collector has no matching relationship"))
+	})
+
+	It("should bracket keys that are not identifiers", func() {
+		dangling := strings.NewReplacer(`"a1"`, `"node-1"`, `"targetID": "b2"`, `"targetID": "c3"`).Replace(validDocument)
+		Expect(validate(dangling, Rules{})).To(ConsistOf(`$.vertexes["node-1"].edgesOut[0].targetID: no vertex "c3"`))
+	})
+
+	It("should list the findings when the matcher fails", func() {
+		dangling := strings.Replace(validDocument, `"targetID": "b2"`, `"targetID": "c3"`, 1)
+		matcher := BeValidClout(Rules{})
+		Expect(matcher.Match(dangling)).To(BeFalse())
+		Expect(matcher.FailureMessage(dangling)).To(ContainSubstring(`$.vertexes.a1.edgesOut[0].targetID: no vertex "c3"`))
+	})
+
+	It("should reject a document that is not a clout", func() {
+		_, err := Validate([]byte(`"not json`), Rules{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,56 @@
+// Command cloutdiff compares two clout files structurally, matching vertexes
+// by TOSCA identity rather than by their generated IDs, and prints one line
+// per added (+), removed (-) or changed (~) node, property or edge.
+//
+// Usage:
+//
+//	cloutdiff [-ignore key,...] before.json after.json
+//
+// Like diff, it exits with 0 when the clouts match, 1 when they differ and
+// 2 when either cannot be read.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"demo2/clout"
+)
+
+func main() {
+	ignore := flag.String("ignore", "", "comma-separated property keys to skip, such as timestamp")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: cloutdiff [-ignore key,...] before.json after.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	before, err := clout.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	after, err := clout.Load(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var ignoredKeys []string
+	if *ignore != "" {
+		ignoredKeys = strings.Split(*ignore, ",")
+	}
+	changes := clout.Diff(before, after, ignoredKeys...)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if len(changes) > 0 {
+		os.Exit(1)
+	}
+}
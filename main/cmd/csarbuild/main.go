@@ -0,0 +1,38 @@
+// Command csarbuild builds a CSAR from a directory of TOSCA YAML, generating
+// TOSCA-Metadata/TOSCA.meta unless the directory brings its own.
+//
+// Usage:
+//
+//	csarbuild [-entry file.yaml] [-created-by name] -o service.csar dir
+//
+// Without -entry, the directory must hold exactly one top-level YAML file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"demo2/csar"
+)
+
+func main() {
+	var options csar.Options
+	output := flag.String("o", "", "archive to write")
+	flag.StringVar(&options.Entry, "entry", "", "entry definitions, relative to dir")
+	flag.StringVar(&options.CreatedBy, "created-by", "", "Created-By of the generated TOSCA.meta")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: csarbuild [-entry file.yaml] [-created-by name] -o service.csar dir")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *output == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := csar.Build(flag.Arg(0), *output, options); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,191 @@
+// Package csar builds CSARs, the zip archives of TOSCA sources the compiler
+// saves models from, out of a directory of TOSCA YAML so that suites can
+// keep their sources in the repo instead of depending on archives that
+// exist only on some machines.
+//
+// A built archive holds every file of the directory plus a generated
+// TOSCA-Metadata/TOSCA.meta naming the entry definitions. A directory that
+// brings its own TOSCA.meta keeps it unchanged, which is how deliberately
+// broken fixtures are built.
+package csar
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MetaPath is where a CSAR keeps its metadata.
+const MetaPath = "TOSCA-Metadata/TOSCA.meta"
+
+// Options control the generated TOSCA.meta.
+type Options struct {
+	// Entry is the entry definitions, relative to the directory. When empty,
+	// the directory must hold exactly one top-level YAML file, which is used.
+	Entry string
+	// CreatedBy defaults to "csarbuild".
+	CreatedBy string
+}
+
+// modTime is stamped on every entry so that building the same sources twice
+// yields the same archive.
+var modTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Meta returns the TOSCA.meta content for entry.
+func Meta(entry string, createdBy string) string {
+	if createdBy == "" {
+		createdBy = "csarbuild"
+	}
+	return "TOSCA-Meta-File-Version: 1.1\n" +
+		"CSAR-Version: 1.1\n" +
+		"Created-By: " + createdBy + "\n" +
+		"Entry-Definitions: " + entry + "\n"
+}
+
+// Build writes the CSAR of the TOSCA sources in dir to target.
+func Build(dir string, target string, options Options) error {
+	files, err := sourceFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	var meta string
+	if !contains(files, MetaPath) {
+		entry, err := entryDefinitions(dir, files, options.Entry)
+		if err != nil {
+			return err
+		}
+		meta = Meta(entry, options.CreatedBy)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	archive := zip.NewWriter(out)
+	if meta != "" {
+		err = writeEntry(archive, MetaPath, strings.NewReader(meta))
+	}
+	for _, name := range files {
+		if err != nil {
+			break
+		}
+		err = copyEntry(archive, dir, name)
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return fmt.Errorf("building %s: %v", target, err)
+	}
+	return nil
+}
+
+// BuildAll builds every subdirectory of root into targetDir as
+// <subdirectory>.csar and returns the archive paths, sorted.
+func BuildAll(root string, targetDir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		target := filepath.Join(targetDir, entry.Name()+".csar")
+		if err := Build(filepath.Join(root, entry.Name()), target, Options{}); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// sourceFiles lists the files under dir as sorted slash-separated paths.
+func sourceFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no TOSCA sources in %s", dir)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func entryDefinitions(dir string, files []string, entry string) (string, error) {
+	if entry != "" {
+		entry = strings.TrimPrefix(filepath.ToSlash(entry), "./")
+		if !contains(files, entry) {
+			return "", fmt.Errorf("entry definitions %s not found in %s", entry, dir)
+		}
+		return entry, nil
+	}
+	var candidates []string
+	for _, name := range files {
+		if !strings.Contains(name, "/") && (strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")) {
+			candidates = append(candidates, name)
+		}
+	}
+	switch len(candidates) {
+	case 1:
+		return candidates[0], nil
+	case 0:
+		return "", fmt.Errorf("no top-level YAML file in %s to use as entry definitions", dir)
+	}
+	return "", errors.New("several top-level YAML files in " + dir + ", choose the entry definitions: " + strings.Join(candidates, ", "))
+}
+
+func copyEntry(archive *zip.Writer, dir string, name string) error {
+	file, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeEntry(archive, name, file)
+}
+
+func writeEntry(archive *zip.Writer, name string, content io.Reader) error {
+	writer, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(writer, content)
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
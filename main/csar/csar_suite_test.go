@@ -0,0 +1,13 @@
+package csar
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCsar(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CSAR Suite")
+}
@@ -0,0 +1,95 @@
+package csar
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSAR", func() {
+	var source, target string
+
+	BeforeEach(func() {
+		source = GinkgoT().TempDir()
+		target = filepath.Join(GinkgoT().TempDir(), "service.csar")
+	})
+
+	write := func(name string, content string) {
+		path := filepath.Join(source, filepath.FromSlash(name))
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	contents := func(path string) map[string]string {
+		archive, err := zip.OpenReader(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer archive.Close()
+		files := map[string]string{}
+		for _, file := range archive.File {
+			reader, err := file.Open()
+			Expect(err).NotTo(HaveOccurred())
+			content, err := ioutil.ReadAll(reader)
+			reader.Close()
+			Expect(err).NotTo(HaveOccurred())
+			files[file.Name] = string(content)
+		}
+		return files
+	}
+
+	It("should generate TOSCA.meta for the only top-level YAML file", func() {
+		write("service.yaml", "tosca_definitions_version: tosca_simple_yaml_1_3\n")
+		write("types/nodes.yaml", "tosca_definitions_version: tosca_simple_yaml_1_3\n")
+		Expect(Build(source, target, Options{CreatedBy: "demo2"})).To(Succeed())
+		files := contents(target)
+		Expect(files).To(HaveLen(3))
+		Expect(files).To(HaveKeyWithValue(MetaPath, Meta("service.yaml", "demo2")))
+		Expect(files[MetaPath]).To(ContainSubstring("Entry-Definitions: service.yaml\n"))
+		Expect(files).To(HaveKey("types/nodes.yaml"))
+	})
+
+	It("should use the given entry definitions", func() {
+		write("service.yaml", "")
+		write("profile.yaml", "")
+		Expect(Build(source, target, Options{})).To(MatchError(ContainSubstring("several top-level YAML files")))
+		Expect(target).NotTo(BeAnExistingFile())
+		Expect(Build(source, target, Options{Entry: "./profile.yaml"})).To(Succeed())
+		Expect(contents(target)[MetaPath]).To(ContainSubstring("Entry-Definitions: profile.yaml\n"))
+	})
+
+	It("should reject a missing entry definitions", func() {
+		write("service.yaml", "")
+		Expect(Build(source, target, Options{Entry: "other.yaml"})).To(MatchError(ContainSubstring("not found")))
+		Expect(Build(GinkgoT().TempDir(), target, Options{})).To(MatchError(ContainSubstring("no TOSCA sources")))
+	})
+
+	It("should keep a TOSCA.meta the sources bring", func() {
+		meta := Meta("definitions/missing.yaml", "demo2")
+		write(MetaPath, meta)
+		write("definitions/types.yaml", "")
+		Expect(Build(source, target, Options{})).To(Succeed())
+		Expect(contents(target)).To(Equal(map[string]string{MetaPath: meta, "definitions/types.yaml": ""}))
+	})
+
+	It("should build the same archive twice", func() {
+		write("service.yaml", "tosca_definitions_version: tosca_simple_yaml_1_3\n")
+		Expect(Build(source, target, Options{})).To(Succeed())
+		first, err := ioutil.ReadFile(target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Build(source, target, Options{})).To(Succeed())
+		Expect(ioutil.ReadFile(target)).To(Equal(first))
+	})
+
+	It("should build every subdirectory", func() {
+		write("first/service.yaml", "")
+		write("second/service.yaml", "")
+		write("README", "")
+		dir := GinkgoT().TempDir()
+		targets, err := BuildAll(source, dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(targets).To(Equal([]string{filepath.Join(dir, "first.csar"), filepath.Join(dir, "second.csar")}))
+	})
+})
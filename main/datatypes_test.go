@@ -0,0 +1,396 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	versionPattern    = regexp.MustCompile(`^\d+\.\d+(\.\d+(\.[0-9A-Za-z_]+(-\d+)?)?)?$`)
+	scalarUnitPattern = regexp.MustCompile(`^\s*[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?\s*([A-Za-z]+)\s*$`)
+)
+
+// scalarUnits lists the units TOSCA accepts for each scalar-unit type, in
+// lower case since unit names are case-insensitive.
+var scalarUnits = map[string][]string{
+	"scalar-unit.size":      {"b", "kb", "kib", "mb", "mib", "gb", "gib", "tb", "tib"},
+	"scalar-unit.time":      {"d", "h", "m", "s", "ms", "us", "ns"},
+	"scalar-unit.frequency": {"hz", "khz", "mhz", "ghz"},
+	"scalar-unit.bitrate":   {"bps", "kbps", "kibps", "mbps", "mibps", "gbps", "gibps", "tbps", "tibps"},
+}
+
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999 -07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// checkDefault reports why value is not a valid default for dataTypeName, or
+// "" when it is. Inputs without a default and types the suite does not know,
+// such as complex data types, are not checked.
+func checkDefault(dataTypeName string, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	switch dataTypeName {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Sprintf("default %v is a %T, not a string", value, value)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Sprintf("default %v is not a whole number", value)
+		}
+	case "float":
+		if _, ok := value.(float64); !ok {
+			return fmt.Sprintf("default %v is not a number", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("default %v is not a boolean", value)
+		}
+	case "list":
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Sprintf("default %v is not an array", value)
+		}
+	case "map":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Sprintf("default %v is not an object", value)
+		}
+	case "range":
+		bounds, ok := value.([]interface{})
+		if !ok || len(bounds) != 2 || !isRangeBound(bounds[0], false) || !isRangeBound(bounds[1], true) {
+			return fmt.Sprintf("default %v is not a two-element range", value)
+		}
+	case "timestamp":
+		text, ok := value.(string)
+		if !ok || !isTimestamp(text) {
+			return fmt.Sprintf("default %v is not a timestamp", value)
+		}
+	case "version":
+		switch version := value.(type) {
+		case float64:
+		case string:
+			if !versionPattern.MatchString(version) {
+				return fmt.Sprintf("default %q is not a version", version)
+			}
+		default:
+			return fmt.Sprintf("default %v is not a version", value)
+		}
+	default:
+		if units, ok := scalarUnits[dataTypeName]; ok {
+			text, _ := value.(string)
+			match := scalarUnitPattern.FindStringSubmatch(text)
+			if match == nil || !containsString(units, strings.ToLower(match[4])) {
+				return fmt.Sprintf("default %v is not a %s", value, dataTypeName)
+			}
+		}
+	}
+	return ""
+}
+
+// isRangeBound reports whether bound is a whole number, or UNBOUNDED where
+// upper is true.
+func isRangeBound(bound interface{}, upper bool) bool {
+	switch bound := bound.(type) {
+	case float64:
+		return bound == math.Trunc(bound)
+	case string:
+		return upper && bound == "UNBOUNDED"
+	}
+	return false
+}
+
+func isTimestamp(text string) bool {
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, text); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dataTypeHistogram counts the inputs of every group by dataTypeName.
+func dataTypeHistogram(data map[string][]EventData) map[string]int {
+	histogram := map[string]int{}
+	for _, events := range data {
+		for _, event := range events {
+			histogram[event.DataTypeName]++
+		}
+	}
+	return histogram
+}
+
+// nonZeroCounts drops the zero counts a fixture may list for completeness.
+func nonZeroCounts(counts map[string]int) map[string]int {
+	nonZero := map[string]int{}
+	for dataTypeName, count := range counts {
+		if count != 0 {
+			nonZero[dataTypeName] = count
+		}
+	}
+	return nonZero
+}
+
+// histogramTable lays out expected and actual counts side by side, marking
+// the dataTypeNames that differ.
+func histogramTable(expected map[string]int, actual map[string]int) string {
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "datatypename\texpected\tactual\t")
+	for _, name := range names {
+		marker := ""
+		if expected[name] != actual[name] {
+			marker = "<--"
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%s\n", name, expected[name], actual[name], marker)
+	}
+	writer.Flush()
+	return table.String()
+}
+
+// checkEntries reports list and map inputs whose entry_schema is missing or
+// whose default entries do not match it.
+func checkEntries(event EventData) []string {
+	if event.DataTypeName != "list" && event.DataTypeName != "map" {
+		return nil
+	}
+	if event.EntrySchema == nil || event.EntrySchema.Type == "" {
+		return []string{fmt.Sprintf("%s input has no entry_schema", event.DataTypeName)}
+	}
+	var entries []interface{}
+	switch value := event.Default.(type) {
+	case []interface{}:
+		entries = value
+	case map[string]interface{}:
+		for _, entry := range value {
+			entries = append(entries, entry)
+		}
+	}
+	var problems []string
+	for _, entry := range entries {
+		if problem := checkDefault(event.EntrySchema.Type, entry); problem != "" {
+			problems = append(problems, "entry "+problem)
+		}
+		for _, problem := range checkConstraints(event.EntrySchema.Constraints, entry) {
+			problems = append(problems, "entry "+problem)
+		}
+	}
+	return problems
+}
+
+// checkConstraints reports the constraint clauses value does not satisfy.
+// Clauses comparing values the suite cannot order, such as scalar units,
+// are skipped.
+func checkConstraints(constraints []Constraint, value interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	var problems []string
+	for _, constraint := range constraints {
+		for operator, argument := range constraint {
+			if !satisfies(operator, argument, value) {
+				problems = append(problems, fmt.Sprintf("default %v violates %s %v", value, operator, argument))
+			}
+		}
+	}
+	return problems
+}
+
+func satisfies(operator string, argument interface{}, value interface{}) bool {
+	switch operator {
+	case "equal":
+		return reflect.DeepEqual(argument, value)
+	case "valid_values":
+		validValues, _ := argument.([]interface{})
+		for _, validValue := range validValues {
+			if reflect.DeepEqual(validValue, value) {
+				return true
+			}
+		}
+		return false
+	case "greater_than", "greater_or_equal", "less_than", "less_or_equal":
+		bound, okBound := argument.(float64)
+		number, okValue := value.(float64)
+		if !okBound || !okValue {
+			return true
+		}
+		switch operator {
+		case "greater_than":
+			return number > bound
+		case "greater_or_equal":
+			return number >= bound
+		case "less_than":
+			return number < bound
+		}
+		return number <= bound
+	case "in_range":
+		bounds, _ := argument.([]interface{})
+		number, ok := value.(float64)
+		if len(bounds) != 2 || !ok {
+			return true
+		}
+		if lower, ok := bounds[0].(float64); ok && number < lower {
+			return false
+		}
+		if upper, ok := bounds[1].(float64); ok && number > upper {
+			return false
+		}
+		return true
+	case "length", "min_length", "max_length":
+		bound, ok := argument.(float64)
+		length, measurable := lengthOf(value)
+		if !ok || !measurable {
+			return true
+		}
+		switch operator {
+		case "length":
+			return float64(length) == bound
+		case "min_length":
+			return float64(length) >= bound
+		}
+		return float64(length) <= bound
+	case "pattern":
+		pattern, okPattern := argument.(string)
+		text, okValue := value.(string)
+		if !okPattern || !okValue {
+			return true
+		}
+		matched, err := regexp.MatchString("^(?:"+pattern+")$", text)
+		return err == nil && matched
+	}
+	return true
+}
+
+func lengthOf(value interface{}) (int, bool) {
+	switch value := value.(type) {
+	case string:
+		return len([]rune(value)), true
+	case []interface{}:
+		return len(value), true
+	case map[string]interface{}:
+		return len(value), true
+	}
+	return 0, false
+}
+
+// checkInputExpectation reports where event differs from the fields its
+// fixture expectation declares.
+func checkInputExpectation(event EventData, expected InputExpectation) []string {
+	var problems []string
+	if expected.DataTypeName != "" && event.DataTypeName != expected.DataTypeName {
+		problems = append(problems, fmt.Sprintf("datatypename is %q, expected %q", event.DataTypeName, expected.DataTypeName))
+	}
+	if expected.EntrySchema != "" {
+		entrySchema := ""
+		if event.EntrySchema != nil {
+			entrySchema = event.EntrySchema.Type
+		}
+		if entrySchema != expected.EntrySchema {
+			problems = append(problems, fmt.Sprintf("entry_schema is %q, expected %q", entrySchema, expected.EntrySchema))
+		}
+	}
+	if expected.Constraints != nil && !reflect.DeepEqual(event.Constraints, expected.Constraints) {
+		problems = append(problems, fmt.Sprintf("constraints are %v, expected %v", event.Constraints, expected.Constraints))
+	}
+	if expected.Required != nil && (event.Required == nil || *event.Required != *expected.Required) {
+		required := "unset"
+		if event.Required != nil {
+			required = fmt.Sprint(*event.Required)
+		}
+		problems = append(problems, fmt.Sprintf("required is %s, expected %t", required, *expected.Required))
+	}
+	if expected.Description != "" && event.Description != expected.Description {
+		problems = append(problems, fmt.Sprintf("description is %q, expected %q", event.Description, expected.Description))
+	}
+	if expected.Metadata != nil && !reflect.DeepEqual(event.Metadata, expected.Metadata) {
+		problems = append(problems, fmt.Sprintf("metadata is %v, expected %v", event.Metadata, expected.Metadata))
+	}
+	return problems
+}
+
+// checkGroups reports groups of the inputs data map that are missing,
+// unexpected, or whose names, dataTypeName counts or size differ from the
+// fixture.
+func checkGroups(data map[string][]EventData, expected map[string]GroupExpectation) []string {
+	var problems []string
+	var keys []string
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	for key := range data {
+		if _, ok := expected[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		events, present := data[key]
+		expectation, declared := expected[key]
+		switch {
+		case !present:
+			problems = append(problems, fmt.Sprintf("group %s: missing", key))
+			continue
+		case !declared:
+			problems = append(problems, fmt.Sprintf("group %s: unexpected, with %d inputs", key, len(events)))
+			continue
+		}
+
+		if expectation.Count != nil && len(events) != *expectation.Count {
+			problems = append(problems, fmt.Sprintf("group %s: has %d inputs, expected %d", key, len(events), *expectation.Count))
+		}
+		if expectation.Names != nil {
+			names := map[string]bool{}
+			for _, event := range events {
+				names[event.Name] = true
+				if !containsString(expectation.Names, event.Name) {
+					problems = append(problems, fmt.Sprintf("group %s: unexpected input %s", key, event.Name))
+				}
+			}
+			for _, name := range expectation.Names {
+				if !names[name] {
+					problems = append(problems, fmt.Sprintf("group %s: missing input %s", key, name))
+				}
+			}
+		}
+		if expectation.DataTypeCounts != nil {
+			wanted := nonZeroCounts(expectation.DataTypeCounts)
+			actual := dataTypeHistogram(map[string][]EventData{key: events})
+			if !reflect.DeepEqual(actual, wanted) {
+				problems = append(problems, fmt.Sprintf("group %s: dataTypeName counts differ:\n%s", key, histogramTable(wanted, actual)))
+			}
+		}
+	}
+	return problems
+}
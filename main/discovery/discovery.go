@@ -0,0 +1,185 @@
+// Package discovery lets suite processes find the services they talk to
+// without relying on fixed ports. Fakes and proxies bind ephemeral ports and
+// publish their address under a name; fixtures refer to that name with a
+// ${name} placeholder which Expand resolves.
+//
+// An address is looked up in this order: the SUITE_<NAME>_ADDR environment
+// variable, the JSON discovery file named by SUITE_DISCOVERY_FILE, and
+// finally the defaults passed by the caller.
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FileEnv names the environment variable holding the discovery file path.
+const FileEnv = "SUITE_DISCOVERY_FILE"
+
+// EnvName returns the environment variable that carries the address of the
+// named service, e.g. "compiler" becomes SUITE_COMPILER_ADDR.
+func EnvName(name string) string {
+	name = strings.ToUpper(name)
+	name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+	return "SUITE_" + name + "_ADDR"
+}
+
+// Lookup returns the published address of the named service.
+func Lookup(name string) (string, bool) {
+	if addr := os.Getenv(EnvName(name)); addr != "" {
+		return addr, true
+	}
+	entries, err := readFile()
+	if err != nil {
+		return "", false
+	}
+	addr, ok := entries[name]
+	return addr, ok
+}
+
+// Publish makes addr discoverable under name for this process and, when a
+// discovery file is configured, for every other process sharing that file.
+func Publish(name string, addr string) error {
+	if err := os.Setenv(EnvName(name), addr); err != nil {
+		return err
+	}
+	path := os.Getenv(FileEnv)
+	if path == "" {
+		return nil
+	}
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := readFile()
+	if err != nil {
+		return err
+	}
+	entries[name] = addr
+	data, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+var placeholder = regexp.MustCompile(`\$\{([^{}:]+)\}`)
+
+// Expand replaces every ${name} placeholder in s with the address published
+// for name, falling back to defaults. Unknown names are reported as an error
+// so that a fixture never silently talks to the wrong host. Placeholders of
+// other kinds, such as ${namespace:CSAR!ENTRY}, are left for their own
+// expander, with any ${name} inside them replaced.
+func Expand(s string, defaults map[string]string) (string, error) {
+	var missing []string
+	expanded := placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		name := placeholder.FindStringSubmatch(match)[1]
+		if addr, ok := Lookup(name); ok {
+			return addr
+		}
+		if addr, ok := defaults[name]; ok {
+			return addr
+		}
+		missing = append(missing, name)
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("no address published for %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// Server is a fake or proxy bound to an ephemeral port.
+type Server struct {
+	Name string
+	Addr string
+	URL  string
+
+	server *http.Server
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// Serve starts handler on an ephemeral loopback port and publishes its
+// address under name.
+func Serve(name string, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := listener.Addr().String()
+	if err := Publish(name, addr); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+	return &Server{Name: name, Addr: addr, URL: "http://" + addr, server: server}, nil
+}
+
+// Proxy starts a reverse proxy to target on an ephemeral port and publishes
+// its address under name.
+func Proxy(name string, target string) (*Server, error) {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	return Serve(name, httputil.NewSingleHostReverseProxy(targetURL))
+}
+
+func readFile() (map[string]string, error) {
+	entries := map[string]string{}
+	path := os.Getenv(FileEnv)
+	if path == "" {
+		return entries, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return entries, nil
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing discovery file %s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// lock serializes writers of the discovery file across suite processes.
+func lock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) || time.Now().After(deadline) {
+			return nil, fmt.Errorf("locking discovery file %s: %v", path, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,102 @@
+package discovery
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Discovery", func() {
+	BeforeEach(func() {
+		os.Unsetenv(EnvName("compiler"))
+		os.Unsetenv(EnvName("fake-inputs"))
+		os.Setenv(FileEnv, filepath.Join(GinkgoT().TempDir(), "discovery.json"))
+		DeferCleanup(os.Unsetenv, FileEnv)
+	})
+
+	It("should derive the environment variable from the service name", func() {
+		Expect(EnvName("fake-inputs")).To(Equal("SUITE_FAKE_INPUTS_ADDR"))
+	})
+
+	It("should fall back to defaults for unpublished names", func() {
+		url, err := Expand("http://${compiler}/compiler/v1/db/models", map[string]string{"compiler": "localhost:10010"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(Equal("http://localhost:10010/compiler/v1/db/models"))
+	})
+
+	It("should leave bare dollar signs alone", func() {
+		text, err := Expand(`{"$primitive": "${compiler}"}`, map[string]string{"compiler": "localhost:10010"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal(`{"$primitive": "localhost:10010"}`))
+	})
+
+	It("should expand names inside other placeholders and leave those alone", func() {
+		text, err := Expand("${namespace:${csars}/dcaf-cmts.csar!dcaf_service.yaml}", map[string]string{"csars": "/tmp/csars"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(text).To(Equal("${namespace:/tmp/csars/dcaf-cmts.csar!dcaf_service.yaml}"))
+	})
+
+	It("should report names that have no address", func() {
+		_, err := Expand("http://${so}/so/v1/instances", nil)
+		Expect(err).To(MatchError(ContainSubstring("so")))
+	})
+
+	It("should prefer the environment over the discovery file", func() {
+		Expect(Publish("compiler", "127.0.0.1:4000")).To(Succeed())
+		os.Setenv(EnvName("compiler"), "127.0.0.1:5000")
+		addr, ok := Lookup("compiler")
+		Expect(ok).To(BeTrue())
+		Expect(addr).To(Equal("127.0.0.1:5000"))
+	})
+
+	It("should share published addresses through the discovery file", func() {
+		Expect(Publish("compiler", "127.0.0.1:4000")).To(Succeed())
+		os.Unsetenv(EnvName("compiler"))
+		addr, ok := Lookup("compiler")
+		Expect(ok).To(BeTrue())
+		Expect(addr).To(Equal("127.0.0.1:4000"))
+	})
+
+	It("should serve fakes on an ephemeral port", func() {
+		server, err := Serve("fake-inputs", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer server.Close()
+
+		url, err := Expand("http://${fake-inputs}/inputs.yaml", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(url).To(Equal(server.URL + "/inputs.yaml"))
+
+		response, err := http.Get(url)
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+		body, err := ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("ok"))
+	})
+
+	It("should proxy to a target through an ephemeral port", func() {
+		target, err := Serve("target", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.Path))
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		defer target.Close()
+
+		proxy, err := Proxy("compiler", target.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer proxy.Close()
+		Expect(proxy.Addr).NotTo(Equal(target.Addr))
+
+		response, err := http.Get(proxy.URL + "/compiler/v1/db/models")
+		Expect(err).NotTo(HaveOccurred())
+		defer response.Body.Close()
+		body, err := ioutil.ReadAll(response.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("/compiler/v1/db/models"))
+	})
+})
@@ -0,0 +1,236 @@
+// Package golden compares API responses against golden files: full
+// responses recorded from a known-good deployment, normalized so that key
+// order does not matter and masked so that UIDs, timestamps and generated
+// names do not make every run differ.
+//
+// Golden files are recorded, or re-recorded after an intended change, by
+// running the suite with -golden.update or with SUITE_GOLDEN_UPDATE=1.
+// Otherwise a snapshot without a golden file fails like any other mismatch.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// UpdateEnv names the environment variable that, set to 1, makes Check
+// record golden files instead of comparing against them.
+const UpdateEnv = "SUITE_GOLDEN_UPDATE"
+
+var update = flag.Bool("golden.update", false, "record golden files instead of comparing against them")
+
+// ErrMissing is returned by Check when a snapshot has no golden file yet.
+var ErrMissing = errors.New("no golden file")
+
+// ErrMismatch is returned by Check when a response differs from its golden
+// file.
+var ErrMismatch = errors.New("response differs from")
+
+// Mask replaces volatile parts of a response. A mask with Key replaces the
+// value of every object key of that name; a mask with Pattern replaces
+// every match in strings and object keys, and a mask with Literal every
+// occurrence of that text, such as a directory created for the run.
+// Replacement defaults to <key> or <masked>.
+type Mask struct {
+	Key         string `json:"key"`
+	Pattern     string `json:"pattern"`
+	Literal     string `json:"literal"`
+	Replacement string `json:"replacement"`
+}
+
+// Config is where a suite keeps its golden files and how it masks them.
+// Fixtures carry it verbatim.
+type Config struct {
+	Dir   string `json:"dir"`
+	Masks []Mask `json:"masks"`
+	// SortLists sorts every list, for APIs that return items in no
+	// particular order.
+	SortLists bool `json:"sortLists"`
+}
+
+// Updating reports whether golden files are being recorded.
+func Updating() bool {
+	return *update || os.Getenv(UpdateEnv) == "1"
+}
+
+// Path returns the golden file of the named snapshot.
+func (config Config) Path(name string) string {
+	return filepath.Join(config.Dir, name+".json")
+}
+
+// Check compares the normalized response with the golden file of the named
+// snapshot, or records it when Updating. A mismatch is reported as an error
+// wrapping ErrMismatch and carrying the difference; a snapshot never
+// recorded yields ErrMissing.
+func (config Config) Check(name string, response []byte) error {
+	normalized, err := config.Normalize(response)
+	if err != nil {
+		return fmt.Errorf("normalizing %s: %v", name, err)
+	}
+	path := config.Path(name)
+	if Updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(path, normalized, 0644)
+	}
+	golden, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%w %s, record it with -golden.update or %s=1", ErrMissing, path, UpdateEnv)
+	}
+	if err != nil {
+		return err
+	}
+	golden = bytes.ReplaceAll(golden, []byte("\r\n"), []byte("\n"))
+	if diff := cmp.Diff(string(golden), string(normalized)); diff != "" {
+		return fmt.Errorf("%w %s (-golden +actual):\n%s", ErrMismatch, path, diff)
+	}
+	return nil
+}
+
+// Normalize decodes a JSON response, masks it and encodes it again with
+// sorted keys and indentation, the form golden files are stored in.
+func (config Config) Normalize(response []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(response))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	masker, err := newMasker(config)
+	if err != nil {
+		return nil, err
+	}
+	var normalized bytes.Buffer
+	encoder := json.NewEncoder(&normalized)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(masker.mask(value)); err != nil {
+		return nil, err
+	}
+	return normalized.Bytes(), nil
+}
+
+type pattern struct {
+	regexp      *regexp.Regexp
+	replacement string
+}
+
+type masker struct {
+	keys      map[string]string
+	patterns  []pattern
+	sortLists bool
+}
+
+func newMasker(config Config) (*masker, error) {
+	masker := &masker{keys: map[string]string{}, sortLists: config.SortLists}
+	for _, mask := range config.Masks {
+		switch {
+		case mask.Key != "":
+			replacement := mask.Replacement
+			if replacement == "" {
+				replacement = "<" + mask.Key + ">"
+			}
+			masker.keys[mask.Key] = replacement
+		case mask.Pattern != "" || mask.Literal != "":
+			expression := mask.Pattern
+			if expression == "" {
+				expression = regexp.QuoteMeta(mask.Literal)
+			}
+			compiled, err := regexp.Compile(expression)
+			if err != nil {
+				return nil, fmt.Errorf("mask pattern %s: %v", mask.Pattern, err)
+			}
+			replacement := mask.Replacement
+			if replacement == "" {
+				replacement = "<masked>"
+			}
+			masker.patterns = append(masker.patterns, pattern{compiled, replacement})
+		default:
+			return nil, errors.New("mask has no key, pattern or literal")
+		}
+	}
+	return masker, nil
+}
+
+func (masker *masker) mask(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return masker.maskObject(value)
+	case []interface{}:
+		masked := make([]interface{}, len(value))
+		for i, entry := range value {
+			masked[i] = masker.mask(entry)
+		}
+		if masker.sortLists {
+			sort.SliceStable(masked, func(i, j int) bool {
+				return encode(masked[i]) < encode(masked[j])
+			})
+		}
+		return masked
+	case string:
+		return masker.maskString(value)
+	}
+	return value
+}
+
+// maskObject masks keys and values. Keys that mask to the same string, such
+// as generated IDs, are numbered in the order of their masked values so the
+// result does not depend on what was generated.
+func (masker *masker) maskObject(object map[string]interface{}) map[string]interface{} {
+	type entry struct {
+		value   interface{}
+		encoded string
+	}
+	groups := map[string][]entry{}
+	for key, value := range object {
+		var masked interface{}
+		if replacement, ok := masker.keys[key]; ok {
+			masked = replacement
+		} else {
+			masked = masker.mask(value)
+		}
+		maskedKey := masker.maskString(key)
+		groups[maskedKey] = append(groups[maskedKey], entry{masked, encode(masked)})
+	}
+	result := make(map[string]interface{}, len(object))
+	for key, entries := range groups {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].encoded < entries[j].encoded
+		})
+		for i, entry := range entries {
+			if i == 0 {
+				result[key] = entry.value
+			} else {
+				result[fmt.Sprintf("%s#%d", key, i+1)] = entry.value
+			}
+		}
+	}
+	return result
+}
+
+func (masker *masker) maskString(s string) string {
+	for _, pattern := range masker.patterns {
+		s = pattern.regexp.ReplaceAllLiteralString(s, pattern.replacement)
+	}
+	return s
+}
+
+func encode(value interface{}) string {
+	var builder strings.Builder
+	encoder := json.NewEncoder(&builder)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return builder.String()
+}
@@ -0,0 +1,126 @@
+package golden
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Golden", func() {
+	var config Config
+
+	BeforeEach(func() {
+		os.Unsetenv(UpdateEnv)
+		config = Config{
+			Dir: GinkgoT().TempDir(),
+			Masks: []Mask{
+				{Key: "uid"},
+				{Pattern: `\d{4}-\d{2}-\d{2}T[0-9:.]+Z`, Replacement: "<timestamp>"},
+				{Pattern: `^v[0-9a-f]{6}$`, Replacement: "<id>"},
+			},
+		}
+	})
+
+	record := func(name string, response string) {
+		os.Setenv(UpdateEnv, "1")
+		DeferCleanup(os.Unsetenv, UpdateEnv)
+		Expect(config.Check(name, []byte(response))).To(Succeed())
+		os.Unsetenv(UpdateEnv)
+	}
+
+	It("should mask keys and patterns and sort keys", func() {
+		normalized, err := config.Normalize([]byte(`{"name": "demo1", "uid": "8d1f", "created": "at 2026-10-19T09:00:00.5Z", "count": 1.50}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(normalized)).To(Equal(`{
+    "count": 1.50,
+    "created": "at <timestamp>",
+    "name": "demo1",
+    "uid": "<uid>"
+}
+`))
+	})
+
+	It("should number generated keys by their content", func() {
+		first, err := config.Normalize([]byte(`{"v1a2b3c": {"name": "collector"}, "v9f8e7d": {"name": "cluster"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		second, err := config.Normalize([]byte(`{"v000001": {"name": "cluster"}, "vffffff": {"name": "collector"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(second)).To(Equal(string(first)))
+		Expect(string(first)).To(ContainSubstring(`"<id>#2"`))
+	})
+
+	It("should sort lists when asked to", func() {
+		config.SortLists = true
+		first, _ := config.Normalize([]byte(`["b", "a"]`))
+		second, _ := config.Normalize([]byte(`["a", "b"]`))
+		Expect(first).To(Equal(second))
+	})
+
+	It("should record and then match a snapshot", func() {
+		record("instances", `[{"uid": "1", "name": "demo1"}]`)
+		data, err := ioutil.ReadFile(config.Path("instances"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"uid": "<uid>"`))
+
+		Expect(config.Check("instances", []byte(`[{"name": "demo1", "uid": "2"}]`))).To(Succeed())
+	})
+
+	It("should report what differs from the golden file", func() {
+		record("instances", `[{"uid": "1", "name": "demo1"}]`)
+		err := config.Check("instances", []byte(`[{"uid": "1", "name": "demo2"}]`))
+		Expect(err).To(MatchError(ContainSubstring("demo2")))
+		Expect(err).To(MatchError(ContainSubstring("-golden +actual")))
+	})
+
+	It("should tell a missing golden file apart", func() {
+		err := config.Check("unrecorded", []byte(`{}`))
+		Expect(errors.Is(err, ErrMissing)).To(BeTrue())
+		Expect(errors.Is(config.Check("unrecorded", []byte(`{}`)), ErrMismatch)).To(BeFalse())
+	})
+
+	It("should mask literal text", func() {
+		config.Masks = append(config.Masks, Mask{Literal: "/tmp/csars.1+2", Replacement: "<csars>"})
+		normalized, err := config.Normalize([]byte(`{"url": "zip:file:c:/tmp/csars.1+2/dcaf.csar!/dcaf_service.yaml"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(normalized)).To(ContainSubstring(`"zip:file:c:<csars>/dcaf.csar!/dcaf_service.yaml"`))
+	})
+
+	Describe("MatchGolden", func() {
+		It("should match a recorded snapshot", func() {
+			record("instances", `[{"uid": "1", "name": "demo1"}]`)
+			Expect(`[{"uid": "2", "name": "demo1"}]`).To(MatchGolden(config, "instances"))
+			Expect([]byte(`[{"uid": "2", "name": "demo2"}]`)).NotTo(MatchGolden(config, "instances"))
+		})
+
+		It("should report the difference", func() {
+			record("instances", `[{"uid": "1", "name": "demo1"}]`)
+			matcher := MatchGolden(config, "instances")
+			Expect(matcher.Match(`[{"uid": "1", "name": "demo2"}]`)).To(BeFalse())
+			Expect(matcher.FailureMessage(nil)).To(ContainSubstring("-golden +actual"))
+		})
+
+		It("should fail on a snapshot that was never recorded", func() {
+			_, err := MatchGolden(config, "unrecorded").Match(`{}`)
+			Expect(err).To(MatchError(ErrMissing))
+		})
+
+		It("should record a snapshot while updating", func() {
+			os.Setenv(UpdateEnv, "1")
+			DeferCleanup(os.Unsetenv, UpdateEnv)
+			Expect(`{"name": "demo1"}`).To(MatchGolden(config, "recorded"))
+			Expect(config.Path("recorded")).To(BeAnExistingFile())
+		})
+
+		It("should reject other types", func() {
+			_, err := MatchGolden(config, "instances").Match(42)
+			Expect(err).To(MatchError(ContainSubstring("got int")))
+		})
+	})
+
+	It("should reject responses that are not JSON", func() {
+		Expect(config.Check("instances", []byte(`<html>`))).To(MatchError(ContainSubstring("normalizing instances")))
+	})
+})
@@ -0,0 +1,51 @@
+package golden
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/onsi/gomega/types"
+)
+
+// MatchGolden succeeds when the actual response matches the golden file of
+// the named snapshot, as reported by Check. The actual value may be the
+// response as []byte or string. While Updating it records the response and
+// succeeds; otherwise a snapshot that was never recorded fails.
+func MatchGolden(config Config, name string) types.GomegaMatcher {
+	return &goldenMatcher{config: config, name: name}
+}
+
+type goldenMatcher struct {
+	config   Config
+	name     string
+	mismatch error
+}
+
+func (matcher *goldenMatcher) Match(actual interface{}) (bool, error) {
+	var response []byte
+	switch actual := actual.(type) {
+	case []byte:
+		response = actual
+	case string:
+		response = []byte(actual)
+	default:
+		return false, fmt.Errorf("MatchGolden expects a []byte or string, got %T", actual)
+	}
+	err := matcher.config.Check(matcher.name, response)
+	if errors.Is(err, ErrMismatch) {
+		matcher.mismatch = err
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (matcher *goldenMatcher) FailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected the %s snapshot to match its golden file: %v", matcher.name, matcher.mismatch)
+}
+
+func (matcher *goldenMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected the %s snapshot to differ from its golden file, found it matching", matcher.name)
+}
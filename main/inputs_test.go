@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"demo2/discovery"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+// The inputs spec overrides input values once inline and once through an
+// inputsUrl served from testdata, and checks that both the inputs endpoint
+// and the compiled output carry the overrides. It shares its model with the
+// other Serial specs.
+var _ = Describe("Compiler model inputs", Ordered, Serial, func() {
+	modelInputs := &dcaf_resource.ModelInputsAPI
+	var inputsServer *discovery.Server
+
+	BeforeAll(func() {
+		var err error
+		inputsServer, err = discovery.Serve("inputs", http.FileServer(http.Dir(filepath.Dir(modelInputs.InputsFile))))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(inputsServer.Close)
+	})
+
+	AfterAll(func() {
+		_, err := ApiCall("DELETE", modelInputs.DeleteModelURL, modelInputs.DeleteModelBody)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	saveWithInputs := func(inputs string, inputsURL string) (int, []byte) {
+		apiBody, err := json.Marshal(SaveModelRequest{
+			URL:       modelInputs.CsarURL,
+			Resolve:   true,
+			Quirks:    []string{"data_types.string.permissive"},
+			Output:    modelInputs.Output,
+			Inputs:    inputs,
+			InputsURL: inputsURL,
+			Force:     true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		status, responseBody, err := apiCallStatus("POST", modelInputs.SaveModelURL, string(apiBody))
+		Expect(err).NotTo(HaveOccurred())
+		return status, responseBody
+	}
+
+	expectOverrides := func(overrides map[string]interface{}) {
+		responseBody, err := ApiCall("GET", modelInputs.GetInputsURL, modelInputs.GetInputsBody)
+		Expect(err).NotTo(HaveOccurred())
+		var inputs APIResponseInputs
+		Expect(json.Unmarshal(responseBody, &inputs)).To(Succeed())
+		defaults := map[string]interface{}{}
+		for _, events := range inputs.Data {
+			for _, event := range events {
+				defaults[event.Name] = event.Default
+			}
+		}
+
+		output := readOutput(modelInputs.OutputLocation)
+		for name, value := range overrides {
+			Expect(defaults).To(HaveKeyWithValue(name, value), "Inputs endpoint does not reflect %s", name)
+			Expect(output.Inputs()[name]).To(Equal(value), "Compiled output does not reflect %s", name)
+		}
+	}
+
+	It("should apply inline inputs", func() {
+		inputs, err := json.Marshal(modelInputs.InlineInputs)
+		Expect(err).NotTo(HaveOccurred())
+		status, responseBody := saveWithInputs(string(inputs), "")
+		Expect(status).To(BeNumerically("<", 300), "Save failed: %s", responseBody)
+		expectOverrides(normalizeValues(modelInputs.InlineInputs))
+	})
+
+	It("should apply inputs from inputsUrl", func() {
+		data, err := ioutil.ReadFile(modelInputs.InputsFile)
+		Expect(err).NotTo(HaveOccurred())
+		var fileInputs map[string]interface{}
+		Expect(yaml.Unmarshal(data, &fileInputs)).To(Succeed())
+
+		status, responseBody := saveWithInputs("", inputsServer.URL+"/"+filepath.Base(modelInputs.InputsFile))
+		Expect(status).To(BeNumerically("<", 300), "Save failed: %s", responseBody)
+		expectOverrides(normalizeValues(fileInputs))
+	})
+
+	for _, invalid := range modelInputs.InvalidInputs {
+		invalid := invalid
+		It("should reject "+invalid.Name, func() {
+			inputs, err := json.Marshal(invalid.Inputs)
+			Expect(err).NotTo(HaveOccurred())
+			status, responseBody := saveWithInputs(string(inputs), "")
+			Expect(status).To(BeNumerically(">=", 400), "Save unexpectedly succeeded: %s", responseBody)
+			expectEnvelope(status, responseBody, invalid.Expected)
+		})
+	}
+})
+
+// normalizeValues round-trips values through JSON so that they compare equal
+// to values decoded from a response, whatever format they were read from.
+func normalizeValues(values map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(values)
+	Expect(err).NotTo(HaveOccurred())
+	var normalized map[string]interface{}
+	Expect(json.Unmarshal(data, &normalized)).To(Succeed())
+	return normalized
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The lifecycle spec walks a second model through save and delete on its own,
+// so it never touches the model saved by BeforeSuite. Every step is its own
+// spec so a report shows exactly where the lifecycle broke. It shares its
+// model with the option matrix and versions specs, hence Serial.
+var _ = Describe("Compiler model lifecycle", Ordered, Serial, func() {
+	lifecycle := &dcaf_resource.ModelLifecycleAPI
+	deleted := false
+
+	AfterAll(func() {
+		if !deleted {
+			_, err := ApiCall("DELETE", lifecycle.DeleteModelURL, lifecycle.DeleteModelBody)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should save the model", func() {
+		status, responseBody, err := apiCallStatus("POST", lifecycle.SaveModelURL, lifecycle.SaveModelBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNumerically("<", 300), "Save failed: %s", responseBody)
+	})
+
+	It("should list the model with the expected service_url", func() {
+		Expect(getServiceURLs(lifecycle.ModelsURL)).To(ContainElement(lifecycle.ExpectedServiceURL))
+	})
+
+	It("should have metadata for the model", func() {
+		metadata, found := getModelMetadata(lifecycle.MetadataURL, lifecycle.ExpectedServiceURL)
+		Expect(found).To(BeTrue(), "No metadata for %s", lifecycle.ExpectedServiceURL)
+		Expect(metadata).NotTo(BeEmpty())
+	})
+
+	It("should have inputs for the model", func() {
+		responseBody, err := ApiCall("GET", lifecycle.GetInputsURL, lifecycle.GetInputsBody)
+		Expect(err).NotTo(HaveOccurred())
+		var inputs APIResponseInputs
+		Expect(json.Unmarshal(responseBody, &inputs)).To(Succeed())
+		Expect(inputs.Data).NotTo(BeEmpty())
+	})
+
+	It("should delete the model", func() {
+		status, responseBody, err := apiCallStatus("DELETE", lifecycle.DeleteModelURL, lifecycle.DeleteModelBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNumerically("<", 300), "Delete failed: %s", responseBody)
+		deleted = true
+	})
+
+	It("should no longer list the model", func() {
+		Expect(getServiceURLs(lifecycle.ModelsURL)).NotTo(ContainElement(lifecycle.ExpectedServiceURL))
+	})
+
+	It("should no longer have metadata for the model", func() {
+		_, found := getModelMetadata(lifecycle.MetadataURL, lifecycle.ExpectedServiceURL)
+		Expect(found).To(BeFalse(), "Metadata left behind for %s", lifecycle.ExpectedServiceURL)
+	})
+
+	It("should report the inputs as not found", func() {
+		status, responseBody, err := apiCallStatus("GET", lifecycle.GetInputsURL, lifecycle.GetInputsBody)
+		Expect(err).NotTo(HaveOccurred())
+		expectEnvelope(status, responseBody, lifecycle.ExpectedNotFound)
+	})
+})
+
+// getServiceURLs returns the service_url of every model the compiler lists.
+func getServiceURLs(modelsURL string) []string {
+	responseBody, err := ApiCall("GET", modelsURL, "")
+	Expect(err).NotTo(HaveOccurred())
+	var models APIResponseModels
+	Expect(json.Unmarshal(responseBody, &models)).To(Succeed())
+
+	var serviceURLs []string
+	for _, model := range models.Data.ListOfModels {
+		if serviceURL, ok := model["service_url"].(string); ok {
+			serviceURLs = append(serviceURLs, serviceURL)
+		}
+	}
+	return serviceURLs
+}
+
+// getModelMetadata returns the metadata of the model saved under serviceURL.
+func getModelMetadata(metadataURL string, serviceURL string) (map[string]string, bool) {
+	responseBody, err := ApiCall("GET", metadataURL, "")
+	Expect(err).NotTo(HaveOccurred())
+	var metadata APIResponseMetadata
+	Expect(json.Unmarshal(responseBody, &metadata)).To(Succeed())
+
+	for _, model := range metadata.Data.Models {
+		if model.ServiceURL == serviceURL {
+			return model.Metadata, true
+		}
+	}
+	return nil, false
+}
+
+// expectEnvelope checks a failed response against the status, result and
+// message declared in a fixture. Empty expectations are not checked.
+func expectEnvelope(status int, responseBody []byte, expected ErrorEnvelope) {
+	if expected.Status != 0 {
+		Expect(status).To(Equal(expected.Status), "Unexpected status, body: %s", responseBody)
+	}
+	var envelope APIResponseEnvelope
+	if expected.Result != "" || expected.Message != "" {
+		Expect(json.Unmarshal(responseBody, &envelope)).To(Succeed(), "Response is not an envelope: %s", responseBody)
+	}
+	if expected.Result != "" {
+		Expect(envelope.Result).To(Equal(expected.Result))
+	}
+	if expected.Message != "" {
+		Expect(envelope.Message).To(ContainSubstring(expected.Message))
+	}
+}
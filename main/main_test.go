@@ -3,30 +3,72 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"demo2/clout"
+	"demo2/csar"
+	"demo2/discovery"
+	"demo2/golden"
+	"demo2/namespace"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
 var dcaf_resource Config
 
-func loadConfig() {
+// defaultAddrs are used for fixture placeholders that no fake, proxy or
+// environment variable has published an address for.
+var defaultAddrs = map[string]string{
+	"compiler": "localhost:10010",
+}
+
+func loadConfig(csars string) {
 	data, err := ioutil.ReadFile("dcaf_resource.json")
 	if err != nil {
 		log.Fatalf("Error reading dcaf_resource file: %v", err)
 	}
-	err = json.Unmarshal(data, &dcaf_resource)
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		log.Fatalf("Error locating testdata: %v", err)
+	}
+	// csarsPath is the CSAR directory as the compiler reports it in namespace
+	// URLs, drive letter included, so snapshots can mask it.
+	defaults := map[string]string{
+		"testdata":  filepath.ToSlash(testdata),
+		"csars":     filepath.ToSlash(csars),
+		"csarsPath": namespace.Path(csars),
+	}
+	for name, addr := range defaultAddrs {
+		defaults[name] = addr
+	}
+	expanded, err := discovery.Expand(string(data), defaults)
+	if err != nil {
+		log.Fatalf("Error resolving dcaf_resource file: %v", err)
+	}
+	err = json.Unmarshal([]byte(namespace.Expand(expanded)), &dcaf_resource)
 	if err != nil {
 		log.Fatalf("Error parsing dcaf_resourceig file: %v", err)
 	}
 }
 func TestCompilerApiOperations(t *testing.T) {
 	RegisterFailHandler(Fail)
-	loadConfig()
+	// The archives the fixtures refer to as ${csars}/<name>.csar are built
+	// from the TOSCA sources in testdata/csars for every run.
+	csars, err := filepath.Abs(t.TempDir())
+	if err != nil {
+		t.Fatalf("Error locating the CSAR directory: %v", err)
+	}
+	if _, err := csar.BuildAll(filepath.Join("testdata", "csars"), csars); err != nil {
+		t.Fatalf("Error building the test CSARs: %v", err)
+	}
+	loadConfig(csars)
 	RunSpecs(t, "Compiler Operations Suite")
 }
 
@@ -48,43 +90,72 @@ var _ = Describe("Compiler APIs", func() {
 		Expect(err).NotTo(HaveOccurred())
 	})
 
-	It("should have expected count of dataTypeName Integer", func() {
-		expectedCount := dcaf_resource.InputAPI.IntegerCounts
-		totalCount := 0
+	It("should have the expected dataTypeName histogram", func() {
+		expected := nonZeroCounts(dcaf_resource.InputAPI.DataTypeCounts)
+		actual := dataTypeHistogram(APIResponseInputs.Data)
+		Expect(actual).To(Equal(expected), "dataTypeName counts differ:\n%s", histogramTable(expected, actual))
+	})
+
+	It("should have default values matching their dataTypeName", func() {
+		var violations []string
 		for _, events := range APIResponseInputs.Data {
 			for _, event := range events {
-				if event.DataTypeName == "integer" {
-					totalCount++
+				if problem := checkDefault(event.DataTypeName, event.Default); problem != "" {
+					violations = append(violations, fmt.Sprintf("%s (%s): %s", event.Name, event.Namespace.URL, problem))
 				}
 			}
 		}
-		Expect(totalCount).To(Equal(expectedCount))
+		Expect(violations).To(BeEmpty(), "Invalid default values:\n%s", strings.Join(violations, "\n"))
 	})
 
-	It("should have expected count of dataTypeName String", func() {
-		expectedCount := dcaf_resource.InputAPI.StringCounts
-		totalCount := 0
+	It("should have entries matching the entry_schema of list and map inputs", func() {
+		var violations []string
 		for _, events := range APIResponseInputs.Data {
 			for _, event := range events {
-				if event.DataTypeName == "string" {
-					totalCount++
+				for _, problem := range checkEntries(event) {
+					violations = append(violations, fmt.Sprintf("%s (%s): %s", event.Name, event.Namespace.URL, problem))
 				}
 			}
 		}
-		Expect(totalCount).To(Equal(expectedCount))
+		Expect(violations).To(BeEmpty(), "Invalid entries:\n%s", strings.Join(violations, "\n"))
 	})
 
-	It("should have expected count of dataTypeName List", func() {
-		expectedCount := dcaf_resource.InputAPI.ListCounts
-		totalCount := 0
+	It("should have default values satisfying their constraints", func() {
+		var violations []string
 		for _, events := range APIResponseInputs.Data {
 			for _, event := range events {
-				if event.DataTypeName == "list" {
-					totalCount++
+				for _, problem := range checkConstraints(event.Constraints, event.Default) {
+					violations = append(violations, fmt.Sprintf("%s (%s): %s", event.Name, event.Namespace.URL, problem))
 				}
 			}
 		}
-		Expect(totalCount).To(Equal(expectedCount))
+		Expect(violations).To(BeEmpty(), "Constraint violations:\n%s", strings.Join(violations, "\n"))
+	})
+
+	It("should match the expected schema of each declared input", func() {
+		events := map[string]EventData{}
+		for _, group := range APIResponseInputs.Data {
+			for _, event := range group {
+				events[event.Name] = event
+			}
+		}
+		var violations []string
+		for name, expected := range dcaf_resource.InputAPI.ExpectedInputs {
+			event, ok := events[name]
+			if !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing", name))
+				continue
+			}
+			for _, problem := range checkInputExpectation(event, expected) {
+				violations = append(violations, fmt.Sprintf("%s (%s): %s", name, event.Namespace.URL, problem))
+			}
+		}
+		Expect(violations).To(BeEmpty(), "Inputs differ from their expectations:\n%s", strings.Join(violations, "\n"))
+	})
+
+	It("should have the expected inputs in each group", func() {
+		violations := checkGroups(APIResponseInputs.Data, dcaf_resource.InputAPI.ExpectedGroups)
+		Expect(violations).To(BeEmpty(), "Input groups differ from their expectations:\n%s", strings.Join(violations, "\n"))
 	})
 
 	It("should match expected name for each data object", func() {
@@ -105,24 +176,47 @@ var _ = AfterSuite(func() {
 })
 
 func ApiCall(apiType string, apiURL string, apiBody string) ([]byte, error) {
+	_, responseBody, err := apiCallStatus(apiType, apiURL, apiBody)
+	return responseBody, err
+}
+
+// apiCallStatus is ApiCall for specs that also need the HTTP status code,
+// such as those expecting a not-found or error envelope.
+func apiCallStatus(apiType string, apiURL string, apiBody string) (int, []byte, error) {
 	body := bytes.NewReader([]byte(apiBody))
 	request, err := http.NewRequest(apiType, apiURL, body)
 	if err != nil {
 		log.Printf("Error while API call: %s", err)
+		return 0, nil, err
 	}
 	request.Header.Set("Content-Type", "application/json")
 	client := &http.Client{}
 	response, err := client.Do(request)
 	if err != nil {
 		log.Println("Error client.Do(request):", err)
+		return 0, nil, err
 	}
 	defer response.Body.Close()
 
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		log.Println("Error responseBody:", err)
+		return response.StatusCode, nil, err
 	}
-	return responseBody, nil
+	return response.StatusCode, responseBody, nil
+}
+
+// SaveModelRequest is the body of model/db/save for specs that build it
+// rather than take it verbatim from a fixture.
+type SaveModelRequest struct {
+	URL       string   `json:"url"`
+	Resolve   bool     `json:"resolve"`
+	Coerce    bool     `json:"coerce"`
+	Quirks    []string `json:"quirks"`
+	Output    string   `json:"output"`
+	Inputs    string   `json:"inputs"`
+	InputsURL string   `json:"inputsUrl"`
+	Force     bool     `json:"force"`
 }
 
 type APIResponseInputs struct {
@@ -132,29 +226,183 @@ type APIResponseInputs struct {
 }
 
 type EventData struct {
-	DataTypeName string      `json:"datatypename"`
-	Default      interface{} `json:"default"`
-	Name         string      `json:"name"`
+	DataTypeName string            `json:"datatypename"`
+	Default      interface{}       `json:"default"`
+	Name         string            `json:"name"`
+	EntrySchema  *EntrySchema      `json:"entry_schema"`
+	Constraints  []Constraint      `json:"constraints"`
+	Required     *bool             `json:"required"`
+	Description  string            `json:"description"`
+	Metadata     map[string]string `json:"metadata"`
 	Namespace    struct {
 		URL string `json:"url"`
 	} `json:"namespace"`
 }
 
+// EntrySchema describes the entries of a list or map input.
+type EntrySchema struct {
+	Type        string       `json:"type"`
+	Description string       `json:"description"`
+	Constraints []Constraint `json:"constraints"`
+}
+
+// Constraint is a single TOSCA constraint clause, such as
+// {"valid_values": ["a", "b"]} or {"in_range": [1, 10]}.
+type Constraint map[string]interface{}
+
+// GroupExpectation declares what a fixture expects of one key of the inputs
+// data map. Fields left out of the fixture are not checked.
+type GroupExpectation struct {
+	Names          []string       `json:"names"`
+	DataTypeCounts map[string]int `json:"dataTypeCounts"`
+	Count          *int           `json:"count"`
+}
+
+// InputExpectation declares what a fixture expects of one input. Fields left
+// out of the fixture are not checked.
+type InputExpectation struct {
+	DataTypeName string            `json:"dataTypeName"`
+	EntrySchema  string            `json:"entrySchema"`
+	Constraints  []Constraint      `json:"constraints"`
+	Required     *bool             `json:"required"`
+	Description  string            `json:"description"`
+	Metadata     map[string]string `json:"metadata"`
+}
+
+type APIResponseEnvelope struct {
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
+type APIResponseModels struct {
+	Result  string `json:"result"`
+	Message string `json:"message"`
+	Data    struct {
+		ListOfModels []map[string]interface{} `json:"listOfModels"`
+	} `json:"data"`
+}
+
+type APIResponseTypes struct {
+	Result  string   `json:"result"`
+	Message string   `json:"message"`
+	Data    []string `json:"data"`
+}
+
+type APIResponseMetadata struct {
+	Result  string `json:"result"`
+	Message string `json:"message"`
+	Data    struct {
+		Models []ModelMetadata `json:"models"`
+	} `json:"data"`
+}
+
+type ModelMetadata struct {
+	ServiceURL string            `json:"service_url"`
+	Metadata   map[string]string `json:"metadata"`
+}
+
+// ErrorEnvelope is what a fixture expects back from a failing call.
+type ErrorEnvelope struct {
+	Status  int    `json:"status"`
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
 type Config struct {
 	SaveModelAPI struct {
-		SaveModelURL  string `json:"saveModelURL"`
-		SaveModelBody string `json:"saveModelBody"`
+		SaveModelURL          string            `json:"saveModelURL"`
+		SaveModelBody         string            `json:"saveModelBody"`
+		OutputLocation        OutputLocation    `json:"outputLocation"`
+		ExpectedNodeTemplates map[string]string `json:"expectedNodeTemplates"`
+		OutputIntegrity       clout.Rules       `json:"outputIntegrity"`
 	} `json:"saveModelAPI"`
 	DeleteModelAPI struct {
 		DeleteModelURL  string `json:"deleteModelURL"`
 		DeleteModelBody string `json:"deleteModelBody"`
 	} `json:"deleteModelAPI"`
 	InputAPI struct {
-		GetInputsURL  string          `json:"getInputsURL"`
-		GetInputsBody string          `json:"getInputsBody"`
-		IntegerCounts int             `json:"integerCounts"`
-		StringCounts  int             `json:"stringCounts"`
-		ListCounts    int             `json:"listCounts"`
-		ExpectedNames map[string]bool `json:"expectedNames"`
+		GetInputsURL   string                      `json:"getInputsURL"`
+		GetInputsBody  string                      `json:"getInputsBody"`
+		DataTypeCounts map[string]int              `json:"dataTypeCounts"`
+		ExpectedNames  map[string]bool             `json:"expectedNames"`
+		ExpectedInputs map[string]InputExpectation `json:"expectedInputs"`
+		ExpectedGroups map[string]GroupExpectation `json:"expectedGroups"`
 	} `json:"getInputAPI"`
+	ModelLifecycleAPI struct {
+		SaveModelURL       string        `json:"saveModelURL"`
+		SaveModelBody      string        `json:"saveModelBody"`
+		ModelsURL          string        `json:"modelsURL"`
+		MetadataURL        string        `json:"metadataURL"`
+		GetInputsURL       string        `json:"getInputsURL"`
+		GetInputsBody      string        `json:"getInputsBody"`
+		DeleteModelURL     string        `json:"deleteModelURL"`
+		DeleteModelBody    string        `json:"deleteModelBody"`
+		ExpectedServiceURL string        `json:"expectedServiceURL"`
+		ExpectedNotFound   ErrorEnvelope `json:"expectedNotFound"`
+	} `json:"modelLifecycleAPI"`
+	CompilerOptionMatrix struct {
+		SaveModelURL       string         `json:"saveModelURL"`
+		GetInputsURL       string         `json:"getInputsURL"`
+		GetInputsBody      string         `json:"getInputsBody"`
+		MetadataURL        string         `json:"metadataURL"`
+		DeleteModelURL     string         `json:"deleteModelURL"`
+		DeleteModelBody    string         `json:"deleteModelBody"`
+		CsarURL            string         `json:"csarURL"`
+		Output             string         `json:"output"`
+		OutputLocation     OutputLocation `json:"outputLocation"`
+		ExpectedServiceURL string         `json:"expectedServiceURL"`
+		Resolve            []bool         `json:"resolve"`
+		Coerce             []bool         `json:"coerce"`
+		Quirks             [][]string     `json:"quirks"`
+		Expected           map[string]struct {
+			Result        string `json:"result"`
+			InputCount    *int   `json:"inputCount"`
+			MetadataCount *int   `json:"metadataCount"`
+		} `json:"expected"`
+		ExpectedDifferences []struct {
+			Between [2]string `json:"between"`
+			Differ  []string  `json:"differ"`
+			Same    []string  `json:"same"`
+		} `json:"expectedDifferences"`
+	} `json:"compilerOptionMatrix"`
+	ModelVersionsAPI struct {
+		SaveModelURL   string `json:"saveModelURL"`
+		SaveModelBody  string `json:"saveModelBody"`
+		CsarPath       string `json:"csarPath"`
+		ServiceURL     string `json:"serviceURL"`
+		ModelsURL      string `json:"modelsURL"`
+		TypesURL       string `json:"typesURL"`
+		DeleteModelURL string `json:"deleteModelURL"`
+		Versions       []struct {
+			Version         string   `json:"version"`
+			Source          string   `json:"source"`
+			DeleteModelBody string   `json:"deleteModelBody"`
+			PurgeModelBody  string   `json:"purgeModelBody"`
+			OwnTypes        []string `json:"ownTypes"`
+		} `json:"versions"`
+		SharedTypes []string `json:"sharedTypes"`
+	} `json:"modelVersionsAPI"`
+	ModelInputsAPI struct {
+		SaveModelURL    string                 `json:"saveModelURL"`
+		GetInputsURL    string                 `json:"getInputsURL"`
+		GetInputsBody   string                 `json:"getInputsBody"`
+		DeleteModelURL  string                 `json:"deleteModelURL"`
+		DeleteModelBody string                 `json:"deleteModelBody"`
+		CsarURL         string                 `json:"csarURL"`
+		Output          string                 `json:"output"`
+		OutputLocation  OutputLocation         `json:"outputLocation"`
+		InlineInputs    map[string]interface{} `json:"inlineInputs"`
+		InputsFile      string                 `json:"inputsFile"`
+		InvalidInputs   []struct {
+			Name     string                 `json:"name"`
+			Inputs   map[string]interface{} `json:"inputs"`
+			Expected ErrorEnvelope          `json:"expected"`
+		} `json:"invalidInputs"`
+	} `json:"modelInputsAPI"`
+	NegativeSaveCases []struct {
+		Name          string        `json:"name"`
+		SaveModelBody string        `json:"saveModelBody"`
+		Expected      ErrorEnvelope `json:"expected"`
+	} `json:"negativeSaveCases"`
+	Snapshots golden.Config `json:"snapshots"`
 }
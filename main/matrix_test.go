@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"demo2/clout"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// optionSummary is what the matrix records for one combination of compiler
+// options: the compiled output, the inputs as the inputs endpoint reports
+// them, and the model metadata.
+type optionSummary struct {
+	Output   *clout.Clout           `json:"output"`
+	Inputs   map[string]interface{} `json:"inputs"`
+	Metadata map[string]string      `json:"metadata"`
+}
+
+// The option matrix saves the same CSAR once per combination of resolve,
+// coerce and quirks, then checks the differences declared in the fixture.
+// It shares its model with the lifecycle and versions specs, hence Serial.
+// A failing combination does not stop the others from being recorded.
+var _ = Describe("Compiler option matrix", Ordered, Serial, ContinueOnFailure, func() {
+	matrix := &dcaf_resource.CompilerOptionMatrix
+	summaries := map[string]optionSummary{}
+
+	AfterAll(func() {
+		_, err := ApiCall("DELETE", matrix.DeleteModelURL, matrix.DeleteModelBody)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	for _, options := range optionCombinations(matrix.Resolve, matrix.Coerce, matrix.Quirks) {
+		options := options
+		label := options.label()
+		It("should save with "+label, func() {
+			options.URL = matrix.CsarURL
+			options.Output = matrix.Output
+			options.Force = true
+			apiBody, err := json.Marshal(options)
+			Expect(err).NotTo(HaveOccurred())
+
+			responseBody, err := ApiCall("POST", matrix.SaveModelURL, string(apiBody))
+			Expect(err).NotTo(HaveOccurred())
+			var saved APIResponseEnvelope
+			Expect(json.Unmarshal(responseBody, &saved)).To(Succeed())
+			if expected, ok := matrix.Expected[label]; ok && expected.Result != "" {
+				Expect(saved.Result).To(Equal(expected.Result))
+			}
+
+			var summary optionSummary
+			summary.Output = readOutput(matrix.OutputLocation)
+
+			responseBody, err = ApiCall("GET", matrix.GetInputsURL, matrix.GetInputsBody)
+			Expect(err).NotTo(HaveOccurred())
+			var inputs APIResponseInputs
+			Expect(json.Unmarshal(responseBody, &inputs)).To(Succeed())
+			summary.Inputs = map[string]interface{}{}
+			for _, events := range inputs.Data {
+				for _, event := range events {
+					summary.Inputs[event.Name] = map[string]interface{}{
+						"datatypename": event.DataTypeName,
+						"default":      event.Default,
+					}
+				}
+			}
+			summary.Metadata, _ = getModelMetadata(matrix.MetadataURL, matrix.ExpectedServiceURL)
+
+			summaries[label] = summary
+			AddReportEntry(label, summary, ReportEntryVisibilityFailureOrVerbose)
+
+			if expected, ok := matrix.Expected[label]; ok {
+				if expected.InputCount != nil {
+					Expect(summary.Inputs).To(HaveLen(*expected.InputCount))
+				}
+				if expected.MetadataCount != nil {
+					Expect(summary.Metadata).To(HaveLen(*expected.MetadataCount))
+				}
+			}
+		})
+	}
+
+	It("should differ between combinations only where the fixture says so", func() {
+		var failures []string
+		for _, difference := range matrix.ExpectedDifferences {
+			a, okA := summaries[difference.Between[0]]
+			b, okB := summaries[difference.Between[1]]
+			if !okA || !okB {
+				failures = append(failures, fmt.Sprintf("no summary recorded for %v", difference.Between))
+				continue
+			}
+			for _, field := range difference.Differ {
+				if same, err := sameField(a, b, field); err != nil || same {
+					failures = append(failures, fmt.Sprintf("%s should differ between %s and %s", field, difference.Between[0], difference.Between[1]))
+				}
+			}
+			for _, field := range difference.Same {
+				if same, err := sameField(a, b, field); err != nil || !same {
+					failures = append(failures, fmt.Sprintf("%s should match between %s and %s", field, difference.Between[0], difference.Between[1]))
+				}
+			}
+		}
+		Expect(failures).To(BeEmpty(), strings.Join(failures, "\n"))
+	})
+})
+
+// optionCombinations returns every combination of the given option values.
+func optionCombinations(resolve []bool, coerce []bool, quirks [][]string) []SaveModelRequest {
+	var combinations []SaveModelRequest
+	for _, r := range resolve {
+		for _, c := range coerce {
+			for _, q := range quirks {
+				combinations = append(combinations, SaveModelRequest{Resolve: r, Coerce: c, Quirks: q})
+			}
+		}
+	}
+	return combinations
+}
+
+// label names a combination the way the fixture refers to it, for example
+// "resolve=true,coerce=false,quirks=data_types.string.permissive".
+func (r SaveModelRequest) label() string {
+	return fmt.Sprintf("resolve=%t,coerce=%t,quirks=%s", r.Resolve, r.Coerce, strings.Join(r.Quirks, "+"))
+}
+
+// sameField reports whether field is the same in both summaries. Outputs
+// are compared with clout.Diff, so generated vertex IDs do not count.
+func sameField(a optionSummary, b optionSummary, field string) (bool, error) {
+	switch field {
+	case "output":
+		return len(clout.Diff(a.Output, b.Output)) == 0, nil
+	case "inputs":
+		return reflect.DeepEqual(a.Inputs, b.Inputs), nil
+	case "metadata":
+		return reflect.DeepEqual(a.Metadata, b.Metadata), nil
+	}
+	return false, fmt.Errorf("unknown summary field %q", field)
+}
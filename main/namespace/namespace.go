@@ -0,0 +1,60 @@
+// Package namespace builds the namespace URLs the compiler gives saved
+// models, such as zip:file:c:/tosca-models/csars/dcaf-resource.csar!/dcaf_service.yaml,
+// so that fixtures do not hard-code one machine's drive letter or separators.
+package namespace
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// DriveEnv names the environment variable holding the drive the compiler
+// resolves CSAR paths on, for example "c:". It is left unset when the
+// compiler runs on a host without drive letters.
+const DriveEnv = "SUITE_COMPILER_DRIVE"
+
+var drivePattern = regexp.MustCompile(`^([A-Za-z]):`)
+
+// URL returns the namespace URL of the entry service template inside the
+// CSAR at csarPath.
+func URL(csarPath string, entry string) string {
+	entry = strings.TrimLeft(strings.ReplaceAll(entry, `\`, "/"), "/")
+	entry = strings.TrimPrefix(entry, "./")
+	return "zip:file:" + Path(csarPath) + "!/" + entry
+}
+
+// Path normalizes csarPath the way the compiler reports it: forward slashes
+// only, no repeated separators, and a lower-case drive letter, taken from
+// DriveEnv when the path has none of its own.
+func Path(csarPath string) string {
+	path := strings.ReplaceAll(csarPath, `\`, "/")
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	drive := ""
+	if match := drivePattern.FindStringSubmatch(path); match != nil {
+		drive = match[1]
+		path = path[len(match[0]):]
+	} else if match := drivePattern.FindStringSubmatch(os.Getenv(DriveEnv)); match != nil {
+		drive = match[1]
+	}
+	if drive == "" {
+		return path
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return strings.ToLower(drive) + ":" + path
+}
+
+var placeholder = regexp.MustCompile(`\$\{namespace:([^}!]+)!([^}]+)\}`)
+
+// Expand replaces every ${namespace:CSAR!ENTRY} placeholder in s with the
+// namespace URL of ENTRY inside CSAR.
+func Expand(s string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(match string) string {
+		parts := placeholder.FindStringSubmatch(match)
+		return URL(parts[1], parts[2])
+	})
+}
@@ -0,0 +1,44 @@
+package namespace
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Namespace", func() {
+	BeforeEach(func() {
+		os.Unsetenv(DriveEnv)
+	})
+
+	It("should build the URL of a CSAR on a host without drives", func() {
+		Expect(URL("/tosca-models/csars/dcaf-resource.csar", "dcaf_service.yaml")).
+			To(Equal("zip:file:/tosca-models/csars/dcaf-resource.csar!/dcaf_service.yaml"))
+	})
+
+	It("should take the drive from the environment", func() {
+		os.Setenv(DriveEnv, "D:")
+		DeferCleanup(os.Unsetenv, DriveEnv)
+		Expect(URL("/tosca-models/csars/dcaf-resource.csar", "/dcaf_service.yaml")).
+			To(Equal("zip:file:d:/tosca-models/csars/dcaf-resource.csar!/dcaf_service.yaml"))
+	})
+
+	It("should normalize Windows paths", func() {
+		Expect(URL(`C:\tosca-models\\csars\cluster-resource.csar`, `.\cluster_input_service.yaml`)).
+			To(Equal("zip:file:c:/tosca-models/csars/cluster-resource.csar!/cluster_input_service.yaml"))
+	})
+
+	It("should prefer the drive in the path over the environment", func() {
+		os.Setenv(DriveEnv, "d:")
+		DeferCleanup(os.Unsetenv, DriveEnv)
+		Expect(Path("C:/tosca-models/csars/a.csar")).To(Equal("c:/tosca-models/csars/a.csar"))
+	})
+
+	It("should expand placeholders in fixtures", func() {
+		os.Setenv(DriveEnv, "c:")
+		DeferCleanup(os.Unsetenv, DriveEnv)
+		Expect(Expand(`{"namespace": "${namespace:/tosca-models/csars/dcaf-resource.csar!dcaf_service.yaml}", "url": "${compiler}"}`)).
+			To(Equal(`{"namespace": "zip:file:c:/tosca-models/csars/dcaf-resource.csar!/dcaf_service.yaml", "url": "${compiler}"}`))
+	})
+})
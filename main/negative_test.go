@@ -0,0 +1,20 @@
+package main
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// Each negative save case comes from the negativeSaveCases fixture, so new
+// failure modes are added there rather than here.
+var _ = Describe("Compiler APIs negative paths", func() {
+	for _, saveCase := range dcaf_resource.NegativeSaveCases {
+		saveCase := saveCase
+		It("should reject a save with "+saveCase.Name, func() {
+			status, responseBody, err := apiCallStatus("POST", dcaf_resource.SaveModelAPI.SaveModelURL, saveCase.SaveModelBody)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(BeNumerically(">=", 400), "Save unexpectedly succeeded: %s", responseBody)
+			expectEnvelope(status, responseBody, saveCase.Expected)
+		})
+	}
+})
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"demo2/clout"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The output spec reads the clout the compiler wrote for the model saved in
+// BeforeSuite, so compile regressions show up even when the DB view of the
+// model looks right.
+var _ = Describe("Compiler output", func() {
+	var output *clout.Clout
+	BeforeEach(func() {
+		output = readOutput(dcaf_resource.SaveModelAPI.OutputLocation)
+	})
+
+	It("should be a clout", func() {
+		Expect(output.Version).NotTo(BeEmpty())
+		Expect(output.Vertexes).NotTo(BeEmpty())
+	})
+
+	It("should pass the integrity checks", func() {
+		Expect(output).To(clout.BeValidClout(dcaf_resource.SaveModelAPI.OutputIntegrity))
+	})
+
+	It("should have the expected node templates", func() {
+		nodeTemplates := map[string][]string{}
+		for _, nodeTemplate := range output.NodeTemplates() {
+			nodeTemplates[nodeTemplate.Name()] = nodeTemplate.Types()
+		}
+		var problems []string
+		for name, nodeType := range dcaf_resource.SaveModelAPI.ExpectedNodeTemplates {
+			types, ok := nodeTemplates[name]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("%s: missing", name))
+			case !containsString(types, nodeType):
+				problems = append(problems, fmt.Sprintf("%s: has types %v, expected %s", name, types, nodeType))
+			}
+		}
+		for name, types := range nodeTemplates {
+			if _, ok := dcaf_resource.SaveModelAPI.ExpectedNodeTemplates[name]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: unexpected, with types %v", name, types))
+			}
+		}
+		sort.Strings(problems)
+		if len(problems) > 0 {
+			graph := output.Graph()
+			AddReportEntry("Compiler output topology (DOT)", graph.DOT())
+			AddReportEntry("Compiler output topology (Mermaid)", graph.Mermaid())
+		}
+		Expect(problems).To(BeEmpty(), "Node templates differ:\n%s", strings.Join(problems, "\n"))
+	})
+
+	It("should have the inputs the inputs endpoint reports", func() {
+		responseBody, err := ApiCall("GET", dcaf_resource.InputAPI.GetInputsURL, dcaf_resource.InputAPI.GetInputsBody)
+		Expect(err).NotTo(HaveOccurred())
+		var inputs APIResponseInputs
+		Expect(json.Unmarshal(responseBody, &inputs)).To(Succeed())
+
+		var names []string
+		for _, events := range inputs.Data {
+			for _, event := range events {
+				names = append(names, event.Name)
+				Expect(output.Inputs()[event.Name]).To(Equal(event.Default), "Output value of %s differs", event.Name)
+			}
+		}
+		Expect(output.Inputs()).To(HaveLen(len(names)), "Output has inputs %v, inputs endpoint has %v", output.Inputs(), names)
+	})
+})
+
+// OutputLocation tells the suite where to find the compiler's output after a
+// save: an endpoint to fetch it from, or a path on a filesystem the suite
+// shares with the compiler.
+type OutputLocation struct {
+	URL  string `json:"url"`
+	Body string `json:"body"`
+	Path string `json:"path"`
+}
+
+// readOutput fetches the output clout and decodes it.
+func readOutput(location OutputLocation) *clout.Clout {
+	var data []byte
+	var err error
+	if location.Path != "" {
+		data, err = ioutil.ReadFile(location.Path)
+	} else {
+		data, err = ApiCall("GET", location.URL, location.Body)
+	}
+	Expect(err).NotTo(HaveOccurred())
+
+	output, err := clout.Parse(data)
+	Expect(err).NotTo(HaveOccurred(), "Output is not a clout: %.200s", data)
+	return output
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"demo2/golden"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The snapshot spec compares whole responses against the golden files in
+// testdata/golden, so that changes no targeted spec looks at still show.
+// It reads the model list, which the lifecycle specs change, hence Serial.
+var _ = Describe("Compiler snapshots", Serial, func() {
+	It("should return the recorded inputs", func() {
+		responseBody, err := ApiCall("GET", dcaf_resource.InputAPI.GetInputsURL, dcaf_resource.InputAPI.GetInputsBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responseBody).To(golden.MatchGolden(dcaf_resource.Snapshots, "inputs"))
+	})
+
+	It("should return the recorded metadata", func() {
+		responseBody, err := ApiCall("GET", dcaf_resource.ModelLifecycleAPI.MetadataURL, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(responseBody).To(golden.MatchGolden(dcaf_resource.Snapshots, "metadata"))
+	})
+})
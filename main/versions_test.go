@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+
+	"demo2/csar"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// The versions spec saves two versions of one service template under the
+// same namespace, by building each version's sources into the same CSAR
+// path before saving it. It deletes the first version without its types and
+// the second with them, checking after each step what the compiler kept.
+// Both versions are built from testdata/versions, whose Argo types no other
+// model defines: the shared types are defined by both versions, and each
+// version also defines types the other lacks.
+var _ = Describe("Compiler model versions", Ordered, Serial, func() {
+	versions := &dcaf_resource.ModelVersionsAPI
+	deleted := map[int]bool{}
+
+	BeforeAll(func() {
+		Expect(versions.Versions).To(HaveLen(2), "The versions spec needs exactly two versions")
+	})
+
+	// The includeTypes:false delete leaves the types of the first version
+	// behind, so a version whose types are still listed is saved again and
+	// deleted with its types.
+	AfterAll(func() {
+		for i, version := range versions.Versions {
+			if deleted[i] {
+				if !containsAny(getTypes(versions.TypesURL), version.OwnTypes) {
+					continue
+				}
+				Expect(csar.Build(version.Source, versions.CsarPath, csar.Options{})).To(Succeed())
+				_, err := ApiCall("POST", versions.SaveModelURL, versions.SaveModelBody)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			_, err := ApiCall("DELETE", versions.DeleteModelURL, version.PurgeModelBody)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		types := getTypes(versions.TypesURL)
+		for _, sharedType := range versions.SharedTypes {
+			Expect(types).NotTo(HaveKey(sharedType))
+		}
+		for _, version := range versions.Versions {
+			for _, ownType := range version.OwnTypes {
+				Expect(types).NotTo(HaveKey(ownType), "Type of %s", version.Version)
+			}
+		}
+	})
+
+	It("should save both versions", func() {
+		for _, version := range versions.Versions {
+			Expect(csar.Build(version.Source, versions.CsarPath, csar.Options{})).To(Succeed())
+			status, responseBody, err := apiCallStatus("POST", versions.SaveModelURL, versions.SaveModelBody)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(BeNumerically("<", 300), "Save of %s failed: %s", version.Version, responseBody)
+		}
+	})
+
+	It("should list both versions under one service URL", func() {
+		listed := getModelVersions(versions.ModelsURL)
+		for _, version := range versions.Versions {
+			Expect(listed[versions.ServiceURL]).To(ContainElement(version.Version))
+		}
+	})
+
+	It("should have the shared types and the types of each version", func() {
+		types := getTypes(versions.TypesURL)
+		for _, sharedType := range versions.SharedTypes {
+			Expect(types).To(HaveKey(sharedType))
+		}
+		for _, version := range versions.Versions {
+			for _, ownType := range version.OwnTypes {
+				Expect(types).To(HaveKey(ownType), "Type of %s", version.Version)
+			}
+		}
+	})
+
+	It("should delete the first version without its types", func() {
+		first := versions.Versions[0]
+		status, responseBody, err := apiCallStatus("DELETE", versions.DeleteModelURL, first.DeleteModelBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNumerically("<", 300), "Delete of %s failed: %s", first.Version, responseBody)
+		deleted[0] = true
+	})
+
+	It("should keep the second version", func() {
+		first, second := versions.Versions[0], versions.Versions[1]
+		listed := getModelVersions(versions.ModelsURL)
+		Expect(listed[versions.ServiceURL]).NotTo(ContainElement(first.Version))
+		Expect(listed[versions.ServiceURL]).To(ContainElement(second.Version))
+	})
+
+	It("should keep the types of the first version when includeTypes is false", func() {
+		types := getTypes(versions.TypesURL)
+		for _, sharedType := range versions.SharedTypes {
+			Expect(types).To(HaveKey(sharedType))
+		}
+		// Only the deleted version defines these, so only includeTypes
+		// explains why they are still there.
+		for _, ownType := range versions.Versions[0].OwnTypes {
+			Expect(types).To(HaveKey(ownType))
+		}
+	})
+
+	It("should delete the second version with its types", func() {
+		second := versions.Versions[1]
+		status, responseBody, err := apiCallStatus("DELETE", versions.DeleteModelURL, second.DeleteModelBody)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(BeNumerically("<", 300), "Delete of %s failed: %s", second.Version, responseBody)
+		deleted[1] = true
+	})
+
+	It("should remove the types of the second version when includeTypes is true", func() {
+		types := getTypes(versions.TypesURL)
+		for _, sharedType := range versions.SharedTypes {
+			Expect(types).NotTo(HaveKey(sharedType))
+		}
+		for _, ownType := range versions.Versions[1].OwnTypes {
+			Expect(types).NotTo(HaveKey(ownType))
+		}
+	})
+})
+
+// getModelVersions returns the versions the compiler lists for each
+// service_url.
+func getModelVersions(modelsURL string) map[string][]string {
+	responseBody, err := ApiCall("GET", modelsURL, "")
+	Expect(err).NotTo(HaveOccurred())
+	var models APIResponseModels
+	Expect(json.Unmarshal(responseBody, &models)).To(Succeed())
+
+	versions := map[string][]string{}
+	for _, model := range models.Data.ListOfModels {
+		serviceURL, _ := model["service_url"].(string)
+		version, _ := model["version"].(string)
+		versions[serviceURL] = append(versions[serviceURL], version)
+	}
+	return versions
+}
+
+// getTypes returns the names of the types the compiler lists.
+func getTypes(typesURL string) map[string]bool {
+	responseBody, err := ApiCall("GET", typesURL, "")
+	Expect(err).NotTo(HaveOccurred())
+	var types APIResponseTypes
+	Expect(json.Unmarshal(responseBody, &types)).To(Succeed(), "Unexpected types response: %s", responseBody)
+
+	names := map[string]bool{}
+	for _, name := range types.Data {
+		names[name] = true
+	}
+	return names
+}
+
+// containsAny reports whether types lists any of names.
+func containsAny(types map[string]bool, names []string) bool {
+	for _, name := range names {
+		if types[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,9 @@
-module demo2
+module demo2/gin
 
 go 1.21.4
 
+require demo2 v0.0.0-00010101000000-000000000000
+
 require (
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
 	github.com/go-logr/logr v1.3.0 // indirect
@@ -21,3 +23,7 @@ require (
 	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// The suite shares its helper packages and TOSCA sources with the main
+// module next to it.
+replace demo2 => ../main
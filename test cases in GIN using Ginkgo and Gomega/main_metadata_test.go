@@ -3,26 +3,52 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 
+	"demo2/csar"
+	"demo2/namespace"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+// csars holds the archives built from csarSources. The first parallel
+// process builds them, saves the model and shares the directory with the
+// others.
+var csars string
+
 func TestCompilerApiOperations(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Compiler Operations Suite")
 }
 
+// csarPath returns the path of the built CSAR with the given name.
+func csarPath(name string) string {
+	return filepath.ToSlash(filepath.Join(csars, name+".csar"))
+}
+
 var apiResponse APIResponse
 
-var _ = BeforeSuite(func() {
-	apiURL := "http://localhost:10010/compiler/v1/model/db/save"
-	apiBody := `{
-		"url": "/tosca-models/csars/dcaf-cmts-argo-events.csar",
+var _ = SynchronizedBeforeSuite(func() []byte {
+	dir, err := ioutil.TempDir("", "csars")
+	Expect(err).NotTo(HaveOccurred())
+	csars, err = filepath.Abs(dir)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = csar.BuildAll(csarSources, csars)
+	Expect(err).NotTo(HaveOccurred())
+
+	apiURL := expand("http://${compiler}/compiler/v1/model/db/save")
+	apiBody := fmt.Sprintf(`{
+		"url": %q,
 		"resolve": true,
 		"coerce": false,
 		"quirks": [
@@ -32,35 +58,123 @@ var _ = BeforeSuite(func() {
 		"inputs": "",
 		"inputsUrl": "",
 		"force": true
-	}`
-	_, err := ApiCall("POST", apiURL, apiBody)
+	}`, csarPath("dcaf-cmts-argo-events"))
+	_, err = ApiCall("POST", apiURL, apiBody)
 	Expect(err).NotTo(HaveOccurred())
+	return []byte(csars)
+}, func(data []byte) {
+	csars = string(data)
 })
 
-var _ = AfterSuite(func() {
-	apiURL := "http://localhost:10010/compiler/v1/model/db/dcaf_service"
-	apiBody := `{
-		"namespace": "zip:file:d:/tosca-models/csars/dcaf-cmts-argo-events.csar!/dcaf_service.yaml",
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	apiURL := expand("http://${compiler}/compiler/v1/model/db/dcaf_service")
+	apiBody := fmt.Sprintf(`{
+		"namespace": %q,
 		"version": "tick_profile_1_0",
 		"includeTypes": true
-	}`
+	}`, namespace.URL(csarPath("dcaf-cmts-argo-events"), "dcaf_service.yaml"))
 	var err error
 	apiResponse, err = ApiCall("DELETE", apiURL, apiBody)
 	Expect(err).NotTo(HaveOccurred())
+	Expect(os.RemoveAll(csars)).To(Succeed())
 })
 
 var _ = Describe("API Test GET", func() {
 
 	It("Re-test GET API after saving model", func() {
-		apiURL := "http://localhost:10010/compiler/v1/db/models/metadata"
+		apiURL := expand("http://${compiler}/compiler/v1/db/models/metadata")
 		apiResponse, err := ApiCall("GET", apiURL, ``)
 		Expect(err).NotTo(HaveOccurred())
 
-		// Asserting metadata count
-		Expect(len(apiResponse.Data.Models[0].Metadata)).To(Equal(3))
+		// Asserting metadata content of every model this run saved, each of
+		// which needs an expectation
+		expectedMetadata := loadExpectedMetadata(expectedMetadataFile)
+		var problems []string
+		found := map[string]bool{}
+		for _, model := range apiResponse.Data.Models {
+			if !ownModel(model.ServiceURL) {
+				continue
+			}
+			expected, ok := expectedMetadata[model.ServiceURL]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: no expectation in %s", model.ServiceURL, expectedMetadataFile))
+				continue
+			}
+			found[model.ServiceURL] = true
+			problems = append(problems, checkMetadata(model.ServiceURL, model.Metadata, expected)...)
+		}
+		for serviceURL := range expectedMetadata {
+			if !found[serviceURL] {
+				problems = append(problems, fmt.Sprintf("%s: no metadata returned", serviceURL))
+			}
+		}
+		sort.Strings(problems)
+		Expect(problems).To(BeEmpty(), "Metadata differs from expectations:\n%s", strings.Join(problems, "\n"))
 	})
 })
 
+// expectedMetadataFile holds the complete metadata each saved model must
+// carry. Keys not listed there are reported as extra.
+const expectedMetadataFile = "expected_metadata.json"
+
+// metadataValue is the expectation for one metadata key: either an exact
+// value or a regular expression the whole value must match.
+type metadataValue struct {
+	Exact   string `json:"exact"`
+	Pattern string `json:"pattern"`
+	pattern *regexp.Regexp
+}
+
+// loadExpectedMetadata reads the expectations from path and keys them by
+// service URL, which depends on where the CSARs were built. Patterns are
+// compiled once here.
+func loadExpectedMetadata(path string) map[string]map[string]metadataValue {
+	data, err := ioutil.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	var fixture struct {
+		Models []struct {
+			CSAR     string                   `json:"csar"`
+			Entry    string                   `json:"entry"`
+			Metadata map[string]metadataValue `json:"metadata"`
+		} `json:"models"`
+	}
+	Expect(json.Unmarshal(data, &fixture)).To(Succeed(), "Error decoding %s", path)
+
+	expectedMetadata := map[string]map[string]metadataValue{}
+	for _, model := range fixture.Models {
+		for key, value := range model.Metadata {
+			if value.Pattern != "" {
+				value.pattern, err = regexp.Compile("^(?:" + value.Pattern + ")$")
+				Expect(err).NotTo(HaveOccurred(), "Invalid pattern for %s in %s", key, model.CSAR)
+				model.Metadata[key] = value
+			}
+		}
+		expectedMetadata[namespace.URL(csarPath(model.CSAR), model.Entry)] = model.Metadata
+	}
+	return expectedMetadata
+}
+
+func checkMetadata(serviceURL string, metadata map[string]string, expected map[string]metadataValue) []string {
+	var problems []string
+	for key, value := range expected {
+		actual, ok := metadata[key]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: missing key %s", serviceURL, key))
+		case value.pattern != nil && !value.pattern.MatchString(actual):
+			problems = append(problems, fmt.Sprintf("%s: %s is %q, expected to match %s", serviceURL, key, actual, value.Pattern))
+		case value.pattern == nil && actual != value.Exact:
+			problems = append(problems, fmt.Sprintf("%s: %s is %q, expected %q", serviceURL, key, actual, value.Exact))
+		}
+	}
+	for key, actual := range metadata {
+		if _, ok := expected[key]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: extra key %s = %q", serviceURL, key, actual))
+		}
+	}
+	return problems
+}
+
 func ApiCall(apiType string, apiURL string, apiBody string) (APIResponse, error) {
 	body := bytes.NewReader([]byte(apiBody))
 	request, err := http.NewRequest(apiType, apiURL, body)
@@ -91,7 +205,8 @@ type APIResponse struct {
 	Result string `json:"result"`
 	Data   struct {
 		Models []struct {
-			Metadata map[string]string `json:"metadata"`
+			ServiceURL string            `json:"service_url"`
+			Metadata   map[string]string `json:"metadata"`
 		} `json:"models"`
 	} `json:"data"`
 }
@@ -3,26 +3,49 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"demo2/csar"
+	"demo2/namespace"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+// csars holds the archives built from csarSources. The first parallel
+// process builds them, saves the model and shares the directory with the
+// others.
+var csars string
+
 func TestCompilerApiOperations(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Compiler Operations Suite")
 }
 
+// csarPath returns the path of the built CSAR with the given name.
+func csarPath(name string) string {
+	return filepath.ToSlash(filepath.Join(csars, name+".csar"))
+}
+
 var apiResponse APIResponse
 
-var _ = BeforeSuite(func() {
-	apiURL := "http://localhost:10010/compiler/v1/model/db/save"
-	apiBody := `{
-		"url": "/tosca-models/csars/cluster-resource.csar",
+var _ = SynchronizedBeforeSuite(func() []byte {
+	dir, err := ioutil.TempDir("", "csars")
+	Expect(err).NotTo(HaveOccurred())
+	csars, err = filepath.Abs(dir)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = csar.BuildAll(csarSources, csars)
+	Expect(err).NotTo(HaveOccurred())
+
+	apiURL := expand("http://${compiler}/compiler/v1/model/db/save")
+	apiBody := fmt.Sprintf(`{
+		"url": %q,
 		"resolve": true,
 		"coerce": false,
 		"quirks": [
@@ -32,41 +55,45 @@ var _ = BeforeSuite(func() {
 		"inputs": "",
 		"inputsUrl": "",
 		"force": true
-	}`
-	_, err := ApiCall("POST", apiURL, apiBody)
+	}`, csarPath("cluster-resource"))
+	_, err = ApiCall("POST", apiURL, apiBody)
 	Expect(err).NotTo(HaveOccurred())
+	return []byte(csars)
+}, func(data []byte) {
+	csars = string(data)
 })
 
-var _ = AfterSuite(func() {
-	apiURL := "http://localhost:10010/compiler/v1/model/db/cluster_input_service"
-	apiBody := `{
-		"namespace": "zip:file:c:/tosca-models/csars/cluster-resource.csar!/cluster_input_service.yaml",
+var _ = SynchronizedAfterSuite(func() {}, func() {
+	apiURL := expand("http://${compiler}/compiler/v1/model/db/cluster_input_service")
+	apiBody := fmt.Sprintf(`{
+		"namespace": %q,
 		"version": "tick_profile_1_0",
 		"includeTypes": true
-	}`
+	}`, namespace.URL(csarPath("cluster-resource"), "cluster_input_service.yaml"))
 	var err error
 	apiResponse, err = ApiCall("DELETE", apiURL, apiBody)
 	Expect(err).NotTo(HaveOccurred())
+	Expect(os.RemoveAll(csars)).To(Succeed())
 })
 
 var _ = Describe("API Test GET", func() {
 
 	It("Re-test GET API after saving model", func() {
-		apiURL := "http://localhost:10010/compiler/v1/db/models"
+		apiURL := expand("http://${compiler}/compiler/v1/db/models")
 		apiResponse, err := ApiCall("GET", apiURL, ``)
 		Expect(err).NotTo(HaveOccurred())
 
-		serviceURLs := make([]interface{}, len(apiResponse.Data.ListOfModels))
-		for i, model := range apiResponse.Data.ListOfModels {
+		var serviceURLs []interface{}
+		for _, model := range apiResponse.Data.ListOfModels {
 			if modelMap, ok := model.(map[string]interface{}); ok {
-				if serviceURL, exists := modelMap["service_url"]; exists {
-					serviceURLs[i] = serviceURL
+				if serviceURL, exists := modelMap["service_url"].(string); exists && ownModel(serviceURL) {
+					serviceURLs = append(serviceURLs, serviceURL)
 				}
 			}
 		}
 
 		Expect(serviceURLs).To(ConsistOf(
-			Equal("zip:file:c:/tosca-models/csars/cluster-resource.csar!/cluster_input_service.yaml"),
+			Equal(namespace.URL(csarPath("cluster-resource"), "cluster_input_service.yaml")),
 		))
 	})
 })
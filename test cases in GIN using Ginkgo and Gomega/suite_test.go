@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+
+	"demo2/discovery"
+	"demo2/namespace"
+
+	. "github.com/onsi/gomega"
+)
+
+// defaultAddrs are used for placeholders that no fake, proxy or environment
+// variable has published an address for.
+var defaultAddrs = map[string]string{
+	"compiler": "localhost:10010",
+}
+
+// csarSources is where the main suite keeps the TOSCA sources of the
+// services both suites save.
+var csarSources = filepath.Join("..", "main", "testdata", "csars")
+
+// expand resolves the ${name} placeholders in s through demo2/discovery, so
+// this suite finds the compiler the same way the main suite does.
+func expand(s string) string {
+	expanded, err := discovery.Expand(s, defaultAddrs)
+	Expect(err).NotTo(HaveOccurred())
+	return expanded
+}
+
+// ownModel reports whether the model saved under serviceURL comes from this
+// run's csars directory. The compiler database is shared, so models other
+// suites or earlier runs saved are left out of the checks.
+func ownModel(serviceURL string) bool {
+	return strings.HasPrefix(serviceURL, "zip:file:"+namespace.Path(csars)+"/")
+}